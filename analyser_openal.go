@@ -0,0 +1,204 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+	sync "sync"
+)
+
+// -------------------------------------------------------------------- //
+// AnalyserNode implementation
+// -------------------------------------------------------------------- //
+
+const (
+	// Maximum FFT size of AnalyserNode
+	analyserMaxFFTSize = 32768
+	// Range in dB of byte frequency data
+	analyserMinDecibels = -100
+	analyserMaxDecibels = -30
+)
+
+// analyserNodes holds analysers with inputs, they are captured at each block even if not pulled by
+// destination. Protected by graphMutex.
+var analyserNodes = make(map[*analyserNode]bool)
+
+// analyserNode passes signal unchanged (AL sources are forwarded), the full mix of inputs is captured in
+// software. As rendering is ahead of playback, data are read at the position of current time.
+type analyserNode struct {
+	node
+	// Captured samples (mono), protected by graphMutex
+	samples      []float32
+	written      int64
+	capturedTime float64
+	captureFrame int64
+	input        renderBlock
+	// Analysis state, protected by mutex
+	mutex      sync.Mutex
+	fftSize    int
+	smoothing  float64
+	frame      []float32
+	spectrum   []complex128
+	magnitudes []float64
+	// analysedEnd is the position of last analysed samples, frequency data are computed once per position
+	analysedEnd int64
+}
+
+func (n *analyserNode) FFTSize(size int) error {
+	if err := checkFFTSize(size); err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if size != n.fftSize {
+		n.fftSize = size
+		n.frame = make([]float32, size)
+		n.spectrum = make([]complex128, size)
+		n.magnitudes = make([]float64, size/2)
+		n.analysedEnd = -1
+	}
+	return nil
+}
+
+func (n *analyserNode) FrequencyBinCount() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.fftSize / 2
+}
+
+func (n *analyserNode) SmoothingTimeConstant(value float32) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.smoothing = math.Max(0, math.Min(float64(value), 1))
+}
+
+func (n *analyserNode) GetFloatFrequencyData(data []float32) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.analyse()
+	for i := 0; i < len(data) && i < len(n.magnitudes); i++ {
+		data[i] = float32(20 * math.Log10(n.magnitudes[i]))
+	}
+}
+
+func (n *analyserNode) GetByteFrequencyData(data []byte) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.analyse()
+	scale := 255 / float64(analyserMaxDecibels-analyserMinDecibels)
+	for i := 0; i < len(data) && i < len(n.magnitudes); i++ {
+		v := math.Floor(scale * (20*math.Log10(n.magnitudes[i]) - analyserMinDecibels))
+		data[i] = byte(math.Max(0, math.Min(v, 255)))
+	}
+}
+
+func (n *analyserNode) GetFloatTimeDomainData(data []float32) {
+	n.mutex.Lock()
+	size := n.fftSize
+	n.mutex.Unlock()
+	if len(data) < size {
+		size = len(data)
+	}
+	n.read(data[:size])
+}
+
+// analyse computes smoothed magnitudes of frequency bins as defined by WebAudio specification, must be
+// called with mutex held
+func (n *analyserNode) analyse() {
+	end := n.read(n.frame)
+	if end == n.analysedEnd {
+		return
+	}
+	n.analysedEnd = end
+
+	// Blackman window
+	size := float64(n.fftSize)
+	for i, v := range n.frame {
+		x := float64(i) / size
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*x) + 0.08*math.Cos(4*math.Pi*x)
+		n.spectrum[i] = complex(float64(v)*w, 0)
+	}
+	fft(n.spectrum, false)
+	for k := range n.magnitudes {
+		re, im := real(n.spectrum[k]), imag(n.spectrum[k])
+		magnitude := math.Sqrt(re*re+im*im) / size
+		smoothed := n.smoothing*n.magnitudes[k] + (1-n.smoothing)*magnitude
+		if math.IsNaN(smoothed) || math.IsInf(smoothed, 0) {
+			smoothed = 0
+		}
+		n.magnitudes[k] = smoothed
+	}
+}
+
+// read copies the last captured samples played at current time into data, returns the position of the
+// last sample
+func (n *analyserNode) read(data []float32) int64 {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	length := int64(len(n.samples))
+	latency := int64((n.capturedTime - currentTime()) * renderSampleRate)
+	if max := length - int64(len(data)); latency > max {
+		latency = max
+	}
+	if latency < 0 {
+		latency = 0
+	}
+	end := n.written - latency
+	for i := range data {
+		k := end - int64(len(data)-i)
+		if k < 0 {
+			data[i] = 0
+		} else {
+			data[i] = n.samples[k%length]
+		}
+	}
+	return end
+}
+
+// render passes inputs and captures them
+func (n *analyserNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(out, wet)
+	n.capture()
+}
+
+// capture appends the full mix of inputs of current block to samples, once per block
+func (n *analyserNode) capture() {
+	if n.captureFrame == renderFrame {
+		return
+	}
+	n.captureFrame = renderFrame
+	n.mixInputs(&n.input, false)
+	length := int64(len(n.samples))
+	for i := range n.input[0] {
+		n.samples[n.written%length] = (n.input[0][i] + n.input[1][i]) / 2
+		n.written++
+	}
+	n.capturedTime = renderTime + float64(renderBlockSize)/renderSampleRate
+}
+
+func (n *analyserNode) onConnectStateChanged(connected bool, sources []*sourceProxy) {
+	if len(n.inputs) > 0 {
+		analyserNodes[n] = true
+	} else {
+		delete(analyserNodes, n)
+	}
+	n.node.onConnectStateChanged(connected, sources)
+}
+
+func createAnalyserNode() (AnalyserNode, error) {
+	startRenderEngine()
+	analyser := &analyserNode{
+		node: node{
+			sources: make([]*sourceProxy, 0, sourcePoolSize),
+			to:      make([]connectListener, 0, 1),
+		},
+		// Room for max FFT size and samples rendered ahead of playback
+		samples:   make([]float32, analyserMaxFFTSize+2*renderBufferCount*renderBufferBlocks*renderBlockSize),
+		smoothing: 0.8,
+	}
+	analyser.FFTSize(2048)
+	analyser.process = analyser.render
+	return analyser, nil
+}
@@ -4,6 +4,9 @@ package audio
 
 import (
 	fmt "fmt"
+	io "io"
+	ioutil "io/ioutil"
+	sync "sync"
 
 	tge "github.com/thommil/tge"
 )
@@ -25,6 +28,77 @@ func (p *plugin) GetName() string {
 	return Name
 }
 
+// Channels of tge lifecycle events, audio is suspended on pause and resumed on resume
+const (
+	lifecyclePauseChannel  = "pause"
+	lifecycleResumeChannel = "resume"
+)
+
+// lifecycleMutex protects lifecycleSuspended
+var lifecycleMutex sync.Mutex
+
+// lifecycleSuspended indicates that audio has been suspended by application lifecycle and not by application
+var lifecycleSuspended bool
+
+// onLifecycleEvent is subscribed to tge lifecycle events by both backends
+func onLifecycleEvent(event tge.Event) bool {
+	switch event.Channel() {
+	case lifecyclePauseChannel:
+		lifecycleSuspend()
+	case lifecycleResumeChannel:
+		lifecycleResume()
+	}
+	return false
+}
+
+// lifecycleSuspend suspends audio if running
+func lifecycleSuspend() {
+	lifecycleMutex.Lock()
+	defer lifecycleMutex.Unlock()
+	if lifecycleSuspended || state() != ContextRunning {
+		return
+	}
+	lifecycleSuspended = suspend() == nil
+}
+
+// lifecycleResume resumes audio only if suspended by lifecycleSuspend and not by application meanwhile
+func lifecycleResume() {
+	lifecycleMutex.Lock()
+	defer lifecycleMutex.Unlock()
+	if !lifecycleSuspended {
+		return
+	}
+	lifecycleSuspended = false
+	resume()
+}
+
+// fireEnded calls the ended callback and publishes the EndedEvent
+func fireEnded(n Node, onEnded func()) {
+	if onEnded != nil {
+		onEnded()
+	}
+	if _pluginInstance.runtime != nil {
+		_pluginInstance.runtime.Publish(EndedEvent{Node: n})
+	}
+}
+
+// Range of sample rates accepted by CreateBufferFromPCM, as supported by WebAudio
+const (
+	pcmMinSampleRate = 3000
+	pcmMaxSampleRate = 768000
+)
+
+// exponentialRampMinValue replaces 0 as value of exponential ramps, which cannot reach it
+const exponentialRampMinValue = 0.0001
+
+// checkFFTSize returns an error if size is not a valid FFT size of AnalyserNode
+func checkFFTSize(size int) error {
+	if size < 32 || size > 32768 || size&(size-1) != 0 {
+		return fmt.Errorf("invalid FFT size %d", size)
+	}
+	return nil
+}
+
 // -------------------------------------------------------------------- //
 // API
 // -------------------------------------------------------------------- //
@@ -34,6 +108,21 @@ func (p *plugin) GetName() string {
 type Buffer interface {
 	// Delete the buffer and free associated memory
 	Delete()
+	// Duration returns the duration of the buffer in seconds
+	Duration() float32
+	// SampleRate returns the number of samples per second for each channel
+	SampleRate() int
+	// NumberOfChannels returns the number of channels (1 mono, 2 stereo)
+	NumberOfChannels() int
+	// Length returns the number of samples for each channel
+	Length() int
+	// GetChannelData returns a copy of the samples of the given channel, values are in [-1, 1]
+	GetChannelData(channel int) ([]float32, error)
+	// CopyFromChannel copies the samples of the given channel from startInChannel into destination
+	CopyFromChannel(destination []float32, channel int, startInChannel int) error
+	// CopyToChannel copies the samples from source into the given channel at startInChannel, on Desktop
+	// and Mobile changes are applied to source nodes created afterwards
+	CopyToChannel(source []float32, channel int, startInChannel int) error
 }
 
 // Node interface is a generic interface for representing an audio processing module.
@@ -43,6 +132,39 @@ type Node interface {
 	Connect(node Node) Node
 	// Disconnect the node output from the given node
 	Disconnect(node Node)
+	// ConnectParam connects the node output to the given AudioParam, the signal is mixed down to mono and
+	// added to the param value (audio-rate modulation)
+	ConnectParam(param AudioParam)
+	// DisconnectParam disconnects the node output from the given AudioParam
+	DisconnectParam(param AudioParam)
+}
+
+// AudioParam interface represents an audio-related parameter, its value can be set immediately or changes
+// can be scheduled at precise times of the audio context clock (see CurrentTime())
+//
+// On Desktop & Mobile, params of nodes rendered in software are computed for each frame, but gain and pan
+// applied by OpenAL are updated by steps of 2ms, so very fast changes can sound less smooth than on browsers.
+// See https://developer.mozilla.org/en-US/docs/Web/API/AudioParam
+type AudioParam interface {
+	// Value returns the current value
+	Value() float32
+	// SetValue sets the value immediately
+	SetValue(value float32)
+	// SetValueAtTime schedules a change to value at startTime
+	SetValueAtTime(value float32, startTime float64)
+	// LinearRampToValueAtTime schedules a linear change from previous event to value at endTime
+	LinearRampToValueAtTime(value float32, endTime float64)
+	// ExponentialRampToValueAtTime schedules an exponential change from previous event to value at endTime,
+	// a value of 0 is replaced by 0.0001 (-80dB) to fade out. If previous value is 0 or has a different
+	// sign, it is held until endTime.
+	ExponentialRampToValueAtTime(value float32, endTime float64)
+	// SetTargetAtTime schedules an exponential approach to target starting at startTime, timeConstant is
+	// the time in seconds to reach 63.2% of the change
+	SetTargetAtTime(target float32, startTime float64, timeConstant float32)
+	// SetValueCurveAtTime schedules a linear interpolation over values starting at startTime during duration seconds
+	SetValueCurveAtTime(values []float32, startTime float64, duration float32)
+	// CancelScheduledValues cancels all scheduled changes starting at or after startTime
+	CancelScheduledValues(startTime float64)
 }
 
 // BufferSourceNode interface represents an audio source consisting of in-memory audio data, stored in an AudioBuffer
@@ -50,7 +172,8 @@ type Node interface {
 type BufferSourceNode interface {
 	Node
 	// Start playing node with given settings :
-	//	- delay before playing in seconds, 0 to start immediatly
+	//	- when is the time of the audio context clock to start at (see CurrentTime()), 0 or a past
+	//	  time to start immediatly
 	//	- offset in seconds in buffer, 0 to start from beginning
 	//	- duration of the sample to play in seconds, 0 to play to end
 	//	- loop indicates to play in loops
@@ -59,9 +182,23 @@ type BufferSourceNode interface {
 	//
 	//	... so to just play the sample :
 	//	 Start(0, 0, 0, false, 0, 0)
-	Start(delay, offset, duration float32, loop bool, loopStart, loopEnd float32)
+	//
+	//	... and to play a sample in 1 second :
+	//	 Start(CurrentTime()+1, 0, 0, false, 0, 0)
+	//
+	// Scheduling is sample-accurate on browsers only. On Desktop & Mobile, the clock is not tied to the
+	// playback position of the device and starts are issued by timers, they happen within a few milliseconds
+	// of when (on a 128 frames boundary for sources rendered in software).
+	Start(when float64, offset, duration float32, loop bool, loopStart, loopEnd float32)
 	// Stop playing node
 	Stop()
+	// PlaybackRate sets the speed factor of playback, 1 is normal speed (pitch changes accordingly)
+	PlaybackRate(value float32)
+	// Detune modulates the speed of playback in cents, 1200 is one octave up
+	Detune(cents float32)
+	// OnEnded sets the callback called once playing has ended or node has been stopped, the
+	// callback may be called from a background goroutine and the node must not be used after it
+	OnEnded(callback func())
 }
 
 // MediaElementSourceNode interface represents an external audio source for continuous play (music)
@@ -72,6 +209,17 @@ type MediaElementSourceNode interface {
 	Play(loop bool)
 	// Pause the attached media
 	Pause()
+	// Seek sets the playback position in seconds
+	Seek(seconds float32)
+	// CurrentTime returns the playback position in seconds
+	CurrentTime() float32
+	// Duration returns the media duration in seconds, 0 if unknown
+	Duration() float32
+	// Paused indicates if the media is paused or not started
+	Paused() bool
+	// OnEnded sets the callback called each time the end of the media is reached (not called in loop mode),
+	// the callback may be called from a background goroutine
+	OnEnded(callback func())
 	// Delete node and free associated memory
 	Delete()
 }
@@ -88,6 +236,8 @@ type StereoPannerNode interface {
 	Node
 	// Pan the output from -1 left to 1 right
 	Pan(value float32)
+	// PanParam returns the AudioParam of pan for automation
+	PanParam() AudioParam
 }
 
 // GainNode interface represents a change in volume
@@ -96,13 +246,407 @@ type GainNode interface {
 	Node
 	// Gain changes the output volume from 0 silence to 1 full
 	Gain(value float32)
+	// GainParam returns the AudioParam of gain for automation
+	GainParam() AudioParam
+}
+
+// BiquadFilterType defines the kind of filter applied by a BiquadFilterNode
+type BiquadFilterType int
+
+const (
+	// BiquadLowpass lets frequencies below frequency pass, Q sets the resonance at cutoff
+	BiquadLowpass BiquadFilterType = iota
+	// BiquadHighpass lets frequencies above frequency pass, Q sets the resonance at cutoff
+	BiquadHighpass
+	// BiquadBandpass lets frequencies around frequency pass, Q sets the width of the band
+	BiquadBandpass
+	// BiquadLowshelf boosts or attenuates frequencies below frequency by gain
+	BiquadLowshelf
+	// BiquadHighshelf boosts or attenuates frequencies above frequency by gain
+	BiquadHighshelf
+	// BiquadPeaking boosts or attenuates frequencies around frequency by gain, Q sets the width of the band
+	BiquadPeaking
+	// BiquadNotch removes frequencies around frequency, Q sets the width of the band
+	BiquadNotch
+	// BiquadAllpass lets all frequencies pass but changes the phase around frequency
+	BiquadAllpass
+)
+
+// String implements fmt.Stringer interface, values are the WebAudio type names
+func (t BiquadFilterType) String() string {
+	switch t {
+	case BiquadLowpass:
+		return "lowpass"
+	case BiquadHighpass:
+		return "highpass"
+	case BiquadBandpass:
+		return "bandpass"
+	case BiquadLowshelf:
+		return "lowshelf"
+	case BiquadHighshelf:
+		return "highshelf"
+	case BiquadPeaking:
+		return "peaking"
+	case BiquadNotch:
+		return "notch"
+	case BiquadAllpass:
+		return "allpass"
+	}
+	return fmt.Sprintf("BiquadFilterType(%d)", int(t))
+}
+
+// BiquadFilterNode interface represents a simple low-order filter
+// See https://developer.mozilla.org/en-US/docs/Web/API/BiquadFilterNode
+type BiquadFilterNode interface {
+	Node
+	// Type sets the kind of filter, default is BiquadLowpass
+	Type(filterType BiquadFilterType)
+	// Frequency sets the cutoff or center frequency in Hz, default is 350
+	Frequency(value float32)
+	// FrequencyParam returns the AudioParam of frequency for automation
+	FrequencyParam() AudioParam
+	// Q sets the quality factor of the filter, default is 1 (in dB for lowpass and highpass)
+	Q(value float32)
+	// QParam returns the AudioParam of Q for automation
+	QParam() AudioParam
+	// Gain sets the boost in dB of shelf and peaking filters, default is 0
+	Gain(value float32)
+	// GainParam returns the AudioParam of gain for automation
+	GainParam() AudioParam
+	// Detune modulates the frequency in cents, default is 0
+	Detune(cents float32)
+	// DetuneParam returns the AudioParam of detune for automation
+	DetuneParam() AudioParam
+}
+
+// ConvolverNode interface represents a linear convolution by an impulse response, used for reverb effects
+// See https://developer.mozilla.org/en-US/docs/Web/API/ConvolverNode
+type ConvolverNode interface {
+	Node
+	// SetBuffer replaces the impulse response (mono or stereo), normalize scales it to an equal loudness
+	SetBuffer(ir Buffer, normalize bool) error
+}
+
+// DynamicsCompressorNode interface represents a compression effect, which lowers the volume of the loudest
+// parts of the signal to prevent clipping
+// See https://developer.mozilla.org/en-US/docs/Web/API/DynamicsCompressorNode
+type DynamicsCompressorNode interface {
+	Node
+	// Threshold sets the level in dB above which compression starts, from -100 to 0, default is -24
+	Threshold(value float32)
+	// ThresholdParam returns the AudioParam of threshold for automation
+	ThresholdParam() AudioParam
+	// Knee sets the range in dB above threshold of the smooth transition to compression, from 0 to 40,
+	// default is 30
+	Knee(value float32)
+	// KneeParam returns the AudioParam of knee for automation
+	KneeParam() AudioParam
+	// Ratio sets the change in dB of input for 1dB change of output, from 1 to 20, default is 12
+	Ratio(value float32)
+	// RatioParam returns the AudioParam of ratio for automation
+	RatioParam() AudioParam
+	// Attack sets the time in seconds to reduce the gain by 10dB, from 0 to 1, default is 0.003
+	Attack(value float32)
+	// AttackParam returns the AudioParam of attack for automation
+	AttackParam() AudioParam
+	// Release sets the time in seconds to increase the gain by 10dB, from 0 to 1, default is 0.25
+	Release(value float32)
+	// ReleaseParam returns the AudioParam of release for automation
+	ReleaseParam() AudioParam
+	// Reduction returns the current gain reduction in dB (0 or negative)
+	Reduction() float32
+}
+
+// AnalyserNode interface provides real-time frequency and time-domain analysis of the signal going through it,
+// the signal is passed unchanged to connected nodes
+// See https://developer.mozilla.org/en-US/docs/Web/API/AnalyserNode
+type AnalyserNode interface {
+	Node
+	// FFTSize sets the window size in samples of the analysis, power of 2 from 32 to 32768, default is 2048
+	FFTSize(size int) error
+	// FrequencyBinCount returns the number of values of frequency data (half of FFT size)
+	FrequencyBinCount() int
+	// SmoothingTimeConstant sets the averaging of frequency data with previous ones from 0 (none) to 1,
+	// default is 0.8
+	SmoothingTimeConstant(value float32)
+	// GetFloatFrequencyData copies the current magnitudes in dB of frequency bins into data
+	GetFloatFrequencyData(data []float32)
+	// GetByteFrequencyData copies the current magnitudes of frequency bins into data, the range from -100dB
+	// to -30dB is scaled to [0, 255]
+	GetByteFrequencyData(data []byte)
+	// GetFloatTimeDomainData copies the current waveform into data, values are in [-1, 1]
+	GetFloatTimeDomainData(data []float32)
+}
+
+// OverSampleType defines the oversampling applied by a WaveShaperNode
+type OverSampleType int
+
+const (
+	// OverSampleNone applies the curve on input samples
+	OverSampleNone OverSampleType = iota
+	// OverSample2x applies the curve at twice the sample rate to reduce aliasing
+	OverSample2x
+	// OverSample4x applies the curve at four times the sample rate to reduce aliasing
+	OverSample4x
+)
+
+// String implements fmt.Stringer interface, values are the WebAudio type names
+func (t OverSampleType) String() string {
+	switch t {
+	case OverSampleNone:
+		return "none"
+	case OverSample2x:
+		return "2x"
+	case OverSample4x:
+		return "4x"
+	}
+	return fmt.Sprintf("OverSampleType(%d)", int(t))
+}
+
+// WaveShaperNode interface represents a non-linear distorter, each sample is mapped through a curve
+// See https://developer.mozilla.org/en-US/docs/Web/API/WaveShaperNode
+type WaveShaperNode interface {
+	Node
+	// SetCurve sets the shaping curve, input samples from -1 to 1 are mapped linearly over curve values
+	// (at least 2), nil lets signal pass unchanged
+	SetCurve(curve []float32) error
+	// Oversample sets the oversampling applied when shaping, default is OverSampleNone
+	Oversample(oversample OverSampleType)
+}
+
+// DelayNode interface represents a delay-line, cycles in audio graph must contain a DelayNode
+// See https://developer.mozilla.org/en-US/docs/Web/API/DelayNode
+type DelayNode interface {
+	Node
+	// DelayTime sets the delay in seconds from 0 to maxDelay, default is 0 (at least 1 render
+	// quantum of 128 frames in cycles)
+	DelayTime(value float32)
+	// DelayTimeParam returns the AudioParam of delay time for automation
+	DelayTimeParam() AudioParam
+}
+
+// OscillatorType defines the periodic waveform of an OscillatorNode
+type OscillatorType int
+
+const (
+	// OscillatorSine is a sine wave
+	OscillatorSine OscillatorType = iota
+	// OscillatorSquare is a square wave with a duty cycle of 0.5
+	OscillatorSquare
+	// OscillatorSawtooth is a sawtooth wave
+	OscillatorSawtooth
+	// OscillatorTriangle is a triangle wave
+	OscillatorTriangle
+	// OscillatorCustom is a wave defined by OscillatorNode.SetPeriodicWave()
+	OscillatorCustom
+)
+
+// String implements fmt.Stringer interface, values are the WebAudio type names
+func (t OscillatorType) String() string {
+	switch t {
+	case OscillatorSine:
+		return "sine"
+	case OscillatorSquare:
+		return "square"
+	case OscillatorSawtooth:
+		return "sawtooth"
+	case OscillatorTriangle:
+		return "triangle"
+	case OscillatorCustom:
+		return "custom"
+	}
+	return fmt.Sprintf("OscillatorType(%d)", int(t))
+}
+
+// OscillatorNode interface represents an audio source generating a periodic waveform, synthesis is
+// band-limited to avoid aliasing
+// See https://developer.mozilla.org/en-US/docs/Web/API/OscillatorNode
+type OscillatorNode interface {
+	Node
+	// Start playing at when (time of audio context clock, 0 or a past time to start immediatly), a node
+	// can only be started once
+	Start(when float64)
+	// Stop playing at when (time of audio context clock, 0 or a past time to stop immediatly)
+	Stop(when float64)
+	// Type sets the waveform, default is OscillatorSine (OscillatorCustom is set by SetPeriodicWave())
+	Type(oscillatorType OscillatorType)
+	// Frequency sets the frequency in Hz, default is 440
+	Frequency(value float32)
+	// FrequencyParam returns the AudioParam of frequency for automation
+	FrequencyParam() AudioParam
+	// Detune modulates the frequency in cents, default is 0
+	Detune(cents float32)
+	// DetuneParam returns the AudioParam of detune for automation
+	DetuneParam() AudioParam
+	// SetPeriodicWave sets a custom waveform from its Fourier coefficients, real holds cosine terms and imag
+	// sine terms (index 0 is ignored), the waveform is normalized to a peak of 1
+	SetPeriodicWave(real, imag []float32) error
+	// OnEnded sets the callback called once node has been stopped, the callback may be called from a
+	// background goroutine and the node must not be used after it
+	OnEnded(callback func())
+}
+
+// ConstantSourceNode interface represents an audio source outputting a constant value, mainly used to modulate
+// AudioParams through Node.ConnectParam()
+// See https://developer.mozilla.org/en-US/docs/Web/API/ConstantSourceNode
+type ConstantSourceNode interface {
+	Node
+	// Start playing at when (time of audio context clock, 0 or a past time to start immediatly), a node
+	// can only be started once
+	Start(when float64)
+	// Stop playing at when (time of audio context clock, 0 or a past time to stop immediatly)
+	Stop(when float64)
+	// Offset sets the output value, default is 1
+	Offset(value float32)
+	// OffsetParam returns the AudioParam of offset for automation
+	OffsetParam() AudioParam
+	// OnEnded sets the callback called once node has been stopped, the callback may be called from a
+	// background goroutine and the node must not be used after it
+	OnEnded(callback func())
+}
+
+// EndedEvent is published on the tge runtime when a BufferSourceNode, MediaElementSourceNode, OscillatorNode
+// or ConstantSourceNode has ended
+type EndedEvent struct {
+	// Node is the source node which has ended
+	Node Node
+}
+
+// Channel implements tge.Event interface
+func (e EndedEvent) Channel() string {
+	return "audio.ended"
+}
+
+// ContextState represents the state of the audio system
+type ContextState int
+
+const (
+	// ContextRunning indicates that audio is processed and clock is running
+	ContextRunning ContextState = iota
+	// ContextSuspended indicates that audio processing and clock are suspended
+	ContextSuspended
+	// ContextClosed indicates that audio system has been released
+	ContextClosed
+)
+
+// String implements fmt.Stringer interface
+func (s ContextState) String() string {
+	switch s {
+	case ContextRunning:
+		return "running"
+	case ContextSuspended:
+		return "suspended"
+	case ContextClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("ContextState(%d)", int(s))
+}
+
+// Suspend suspends audio processing, playing sources are paused and the audio context clock is stopped.
+//
+// Audio is suspended automatically when tge publishes application pause and resumed on application resume,
+// on browsers it is also suspended while page is hidden. Audio suspended by Suspend() is only resumed by Resume().
+func Suspend() error {
+	lifecycleMutex.Lock()
+	lifecycleSuspended = false
+	lifecycleMutex.Unlock()
+	return suspend()
+}
+
+// Resume resumes audio processing, sources paused by Suspend() are played again
+func Resume() error {
+	lifecycleMutex.Lock()
+	lifecycleSuspended = false
+	lifecycleMutex.Unlock()
+	return resume()
+}
+
+// Unlock allows audio to be played on browsers, it must be called from a user interaction handler (no-op on
+// Desktop & Mobile).
+//
+// Audio is unlocked automatically on the first click, touch or key press received by tge, calls to
+// BufferSourceNode.Start() and MediaElementSourceNode.Play() issued before are queued until unlock.
+func Unlock() error {
+	return unlock()
+}
+
+// Unlocked indicates if audio has been unlocked (always true on Desktop & Mobile)
+func Unlocked() bool {
+	return unlocked()
+}
+
+// State returns the current state of the audio system
+func State() ContextState {
+	return state()
+}
+
+// SetMasterLimiter enables or disables a brick-wall limiter in front of the destination, it prevents
+// clipping when many sources are played at once (disabled by default).
+//
+// On Desktop & Mobile, all sources are rendered in software while enabled.
+func SetMasterLimiter(enabled bool) error {
+	return setMasterLimiter(enabled)
+}
+
+// CurrentTime returns the time of the audio context clock in seconds, used to schedule AudioParam changes
+// and BufferSourceNode starts. The clock is monotonic, starts at 0 when plugin is initialized and stops while
+// audio is suspended. On Desktop & Mobile, it is based on the system clock and not on the device playback.
+func CurrentTime() float64 {
+	return currentTime()
 }
 
-// CreateBuffer creates a Buffer from an assets path (supports: OGG only)
+// StartAfter plays node after delay seconds with the other settings of BufferSourceNode.Start(), it keeps
+// the behaviour of Start() before it took a time of the audio context clock.
+//
+// Deprecated: use node.Start(CurrentTime()+delay, ...) instead.
+func StartAfter(node BufferSourceNode, delay, offset, duration float32, loop bool, loopStart, loopEnd float32) {
+	when := 0.0
+	if delay > 0 {
+		when = CurrentTime() + float64(delay)
+	}
+	node.Start(when, offset, duration, loop, loopStart, loopEnd)
+}
+
+// CreateBuffer creates a Buffer from an assets path (supports: OGG, WAV, MP3, FLAC)
 func CreateBuffer(path string) (Buffer, error) {
 	return createBuffer(path)
 }
 
+// CreateBufferFromBytes creates a Buffer from encoded audio data in memory, format is the name of a registered
+// Decoder (ie "vorbis", "wav", "mp3", "flac") or empty to detect it from content (ignored on browsers)
+func CreateBufferFromBytes(data []byte, format string) (Buffer, error) {
+	return createBufferFromBytes(data, format)
+}
+
+// CreateBufferFromReader creates a Buffer from encoded audio data read until EOF, format is detected from content
+func CreateBufferFromReader(reader io.Reader) (Buffer, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return createBufferFromBytes(data, "")
+}
+
+// CreateBufferFromPCM creates a Buffer from raw samples, one slice per channel with values in [-1, 1] (mono or stereo),
+// sample rate must be in [3000, 768000] Hz
+func CreateBufferFromPCM(channels [][]float32, sampleRate int) (Buffer, error) {
+	if len(channels) == 0 || len(channels) > 2 {
+		return nil, fmt.Errorf("invalid number of channels %d", len(channels))
+	}
+	if len(channels[0]) == 0 {
+		return nil, fmt.Errorf("channels must not be empty")
+	}
+	for _, channel := range channels[1:] {
+		if len(channel) != len(channels[0]) {
+			return nil, fmt.Errorf("channels must have the same length")
+		}
+	}
+	if sampleRate < pcmMinSampleRate || sampleRate > pcmMaxSampleRate {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	return createBufferFromPCM(channels, sampleRate)
+}
+
 // CreateNode creates a new custom node, not implemented yet
 func CreateNode() (Node, error) {
 	return nil, fmt.Errorf("not implemented yet")
@@ -113,11 +657,23 @@ func CreateBufferSourceNode(buffer Buffer) (BufferSourceNode, error) {
 	return createBufferSourceNode(buffer)
 }
 
-// CreateMediaElementSourceNode creates a new MediaElementSourceNode from an assets path (supports: OGG only)
+// CreateMediaElementSourceNode creates a new MediaElementSourceNode from an assets path (supports: OGG, WAV, MP3, FLAC)
 func CreateMediaElementSourceNode(path string) (MediaElementSourceNode, error) {
 	return createMediaElementSourceNode(path)
 }
 
+// CreateOscillatorNode creates a new OscillatorNode, this method must be called each time you want to play an
+// OscillatorNode
+func CreateOscillatorNode() (OscillatorNode, error) {
+	return createOscillatorNode()
+}
+
+// CreateConstantSourceNode creates a new ConstantSourceNode, this method must be called each time you want to
+// play a ConstantSourceNode
+func CreateConstantSourceNode() (ConstantSourceNode, error) {
+	return createConstantSourceNode()
+}
+
 // CreateDestinationNode creates a new DestinationNode to connect audio graph output
 func CreateDestinationNode() (DestinationNode, error) {
 	return createDestinationNode()
@@ -132,3 +688,38 @@ func CreateStereoPannerNode() (StereoPannerNode, error) {
 func CreateGainNode() (GainNode, error) {
 	return createGainNode()
 }
+
+// CreateBiquadFilterNode creates a new BiquadFilterNode to connect in audio graph
+func CreateBiquadFilterNode() (BiquadFilterNode, error) {
+	return createBiquadFilterNode()
+}
+
+// CreateConvolverNode creates a new ConvolverNode to connect in audio graph, ir is the impulse response (mono
+// or stereo) and normalize scales it to an equal loudness
+func CreateConvolverNode(ir Buffer, normalize bool) (ConvolverNode, error) {
+	return createConvolverNode(ir, normalize)
+}
+
+// CreateDynamicsCompressorNode creates a new DynamicsCompressorNode to connect in audio graph
+func CreateDynamicsCompressorNode() (DynamicsCompressorNode, error) {
+	return createDynamicsCompressorNode()
+}
+
+// CreateAnalyserNode creates a new AnalyserNode to connect in audio graph
+func CreateAnalyserNode() (AnalyserNode, error) {
+	return createAnalyserNode()
+}
+
+// CreateWaveShaperNode creates a new WaveShaperNode to connect in audio graph
+func CreateWaveShaperNode() (WaveShaperNode, error) {
+	return createWaveShaperNode()
+}
+
+// CreateDelayNode creates a new DelayNode to connect in audio graph, maxDelay is the maximum delay time in
+// seconds (up to 180)
+func CreateDelayNode(maxDelay float32) (DelayNode, error) {
+	if maxDelay <= 0 || maxDelay >= 180 {
+		return nil, fmt.Errorf("invalid max delay %v", maxDelay)
+	}
+	return createDelayNode(maxDelay)
+}
@@ -8,15 +8,15 @@ package audio
 import (
 	"encoding/binary"
 	fmt "fmt"
+	io "io"
 	"math"
-	filepath "path/filepath"
+	sync "sync"
+	atomic "sync/atomic"
 	time "time"
 	unsafe "unsafe"
 
 	tge "github.com/thommil/tge"
 	al "github.com/thommil/tge-mobile/exp/audio/al"
-
-	vorbis "github.com/mccoyst/vorbis"
 )
 
 type plugin struct {
@@ -29,6 +29,7 @@ var nativeEndian binary.ByteOrder
 func (p *plugin) Init(runtime tge.Runtime) error {
 	if !p.isInit {
 		p.runtime = runtime
+		resetClock()
 
 		buf := [2]byte{}
 		*(*uint16)(unsafe.Pointer(&buf[0])) = uint16(0xABCD)
@@ -45,13 +46,10 @@ func (p *plugin) Init(runtime tge.Runtime) error {
 		}
 
 		al.SetDistanceModel(al.LinearDistanceClamped)
+		destinationNodeSingleton.process = destinationNodeSingleton.mixInputs
 		sources := al.GenSources(sourcePoolSize)
 		for _, source := range sources {
-			source.SetMaxGain(1.0)
-			source.SetGain(0)
-			source.Setf(0x202, 1)    //AL_SOURCE_RELATIVE
-			source.Setf(0x1023, 1.5) //AL_MAX_DISTANCE
-			source.Setf(0x1020, 0.5) //AL_REFERENCE_DISTANCE
+			setupSource(source)
 			b := bufferSourceNode{
 				node: node{
 					sources: []*sourceProxy{&sourceProxy{
@@ -62,9 +60,18 @@ func (p *plugin) Init(runtime tge.Runtime) error {
 					}},
 					to: make([]connectListener, 0, 1),
 				},
+				playbackRate: 1,
 			}
+			b.process = b.render
+			bufferSourceNodes = append(bufferSourceNodes, &b)
 			sourcePool <- &b
 		}
+		contextMutex.Lock()
+		contextState = ContextRunning
+		contextMutex.Unlock()
+		runtime.Subscribe(lifecyclePauseChannel, onLifecycleEvent)
+		runtime.Subscribe(lifecycleResumeChannel, onLifecycleEvent)
+		p.isInit = true
 		return nil
 	}
 	return fmt.Errorf("Already initialized")
@@ -72,7 +79,15 @@ func (p *plugin) Init(runtime tge.Runtime) error {
 
 func (p *plugin) Dispose() {
 	if p.isInit {
+		p.runtime.Unsubscribe(lifecyclePauseChannel, onLifecycleEvent)
+		p.runtime.Unsubscribe(lifecycleResumeChannel, onLifecycleEvent)
+		contextMutex.Lock()
+		contextState = ContextClosed
+		suspendedSources, suspendedMediaElements = nil, nil
+		contextMutex.Unlock()
+		stopRenderEngine()
 		al.CloseDevice()
+		p.isInit = false
 		p.runtime = nil
 	}
 }
@@ -92,30 +107,206 @@ var destinationNodeSingleton = destinationNode{
 	},
 }
 
+// Context
+
+var contextMutex sync.Mutex
+var contextState = ContextSuspended
+
+// bufferSourceNodes holds all pooled nodes
+var bufferSourceNodes = make([]*bufferSourceNode, 0, sourcePoolSize)
+
+// mediaElementNodes holds media elements not deleted
+var mediaElementNodes = make(map[*mediaElementSourceNode]bool)
+
+// suspendedSources and suspendedMediaElements hold sources paused by suspend
+var suspendedSources []al.Source
+var suspendedMediaElements []*mediaElementSourceNode
+
+func suspend() error {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+	switch contextState {
+	case ContextClosed:
+		return fmt.Errorf("audio context is closed")
+	case ContextSuspended:
+		return nil
+	}
+	pauseClock()
+	suspendedSources = suspendedSources[:0]
+	for _, n := range bufferSourceNodes {
+		if n.sources[0].handle.State() == al.Playing {
+			suspendedSources = append(suspendedSources, n.sources[0].handle)
+		}
+	}
+	if len(suspendedSources) > 0 {
+		al.PauseSources(suspendedSources...)
+	}
+	suspendedMediaElements = suspendedMediaElements[:0]
+	for n := range mediaElementNodes {
+		if !n.Paused() {
+			n.pause()
+			suspendedMediaElements = append(suspendedMediaElements, n)
+		}
+	}
+	contextState = ContextSuspended
+	return nil
+}
+
+func resume() error {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+	switch contextState {
+	case ContextClosed:
+		return fmt.Errorf("audio context is closed")
+	case ContextRunning:
+		return nil
+	}
+	contextState = ContextRunning
+	// Only resume sources still paused, stopped ones have been recycled
+	sources := suspendedSources[:0]
+	for _, source := range suspendedSources {
+		if source.State() == al.Paused {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) > 0 {
+		al.PlaySources(sources...)
+	}
+	for _, n := range suspendedMediaElements {
+		n.mutex.Lock()
+		loop := n.loop
+		n.mutex.Unlock()
+		n.play(loop)
+	}
+	suspendedSources, suspendedMediaElements = suspendedSources[:0], suspendedMediaElements[:0]
+	resumeClock()
+	return nil
+}
+
+// Audio is never locked on Desktop & Mobile
+func unlock() error {
+	return nil
+}
+
+func unlocked() bool {
+	return true
+}
+
+// removeSuspendedMediaElement removes a media element from the ones to play on resume, contextMutex must be held
+func removeSuspendedMediaElement(n *mediaElementSourceNode) {
+	for i, suspended := range suspendedMediaElements {
+		if suspended == n {
+			suspendedMediaElements = append(suspendedMediaElements[:i], suspendedMediaElements[i+1:]...)
+			return
+		}
+	}
+}
+
+func state() ContextState {
+	contextMutex.Lock()
+	defer contextMutex.Unlock()
+	return contextState
+}
+
 // GRAPH
 
+// graphMutex protects graph state updated from background goroutines (automation)
+var graphMutex sync.Mutex
+
 type connectListener interface {
 	onConnectStateChanged(connected bool, sources []*sourceProxy)
+	renderNode() *node
 }
 
 type node struct {
 	sources []*sourceProxy
 	to      []connectListener
+	// inputs holds connected nodes, pulled by software rendering
+	inputs []*node
+	// params holds connected params
+	params []*audioParam
+	// process renders the output of node for current block in software, nil for silent nodes
+	process func(out *renderBlock, wet bool)
+	// rendered indicates that output is fully rendered in software, wet output is the full output
+	rendered bool
+	// cycle returns the output of node when pulled again while rendering, nil to mute cycles
+	cycle func() *renderBlock
+	// caches holds full and wet outputs of current block
+	caches [2]renderCache
 }
 
 func (n *node) Connect(to Node) Node {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
 	n.to = append(n.to, to.(connectListener))
+	toNode := to.(connectListener).renderNode()
+	toNode.inputs = append(toNode.inputs, n)
 	to.(connectListener).onConnectStateChanged(true, n.sources)
 	return to
 }
 
 func (n *node) Disconnect(to Node) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.disconnect(to.(connectListener))
+}
+
+func (n *node) ConnectParam(param AudioParam) {
+	startRenderEngine()
+	p := param.(*audioParam)
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	p.connect(n)
+	n.params = append(n.params, p)
+}
+
+func (n *node) DisconnectParam(param AudioParam) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.disconnectParam(param.(*audioParam))
+}
+
+// disconnectParam removes a connection to param, graphMutex must be held
+func (n *node) disconnectParam(p *audioParam) {
+	for i, currentParam := range n.params {
+		if currentParam == p {
+			n.params = append(n.params[:i], n.params[i+1:]...)
+			p.disconnect(n)
+			return
+		}
+	}
+}
+
+// disconnect removes a connection, graphMutex must be held
+func (n *node) disconnect(to connectListener) {
 	for i, currentToNode := range n.to {
-		if currentToNode == to.(connectListener) {
+		if currentToNode == to {
 			n.to = append(n.to[:i], n.to[i+1:]...)
+			break
 		}
 	}
-	to.(connectListener).onConnectStateChanged(false, n.sources)
+	toNode := to.renderNode()
+	for i, input := range toNode.inputs {
+		if input == n {
+			toNode.inputs = append(toNode.inputs[:i], toNode.inputs[i+1:]...)
+			break
+		}
+	}
+	to.onConnectStateChanged(false, n.sources)
+}
+
+// disconnectAll removes all connections of node, graphMutex must be held
+func (n *node) disconnectAll() {
+	for len(n.to) > 0 {
+		n.disconnect(n.to[len(n.to)-1])
+	}
+	for len(n.params) > 0 {
+		n.disconnectParam(n.params[len(n.params)-1])
+	}
+}
+
+func (n *node) renderNode() *node {
+	return n
 }
 
 func (n *node) onConnectStateChanged(connected bool, sources []*sourceProxy) {
@@ -138,12 +329,122 @@ func (n *node) onConnectStateChanged(connected bool, sources []*sourceProxy) {
 // Buffer
 
 type buffer struct {
-	handle   al.Buffer
-	duration time.Duration
+	handle     al.Buffer
+	duration   time.Duration
+	sampleRate int
+	channels   int
+	length     int
+	// CPU side copy of samples, one slice per channel, nil once deleted (protected by graphMutex)
+	data [][]float32
+	// dirty indicates that data has changed since last upload
+	dirty bool
+	// retired holds AL buffers replaced while still in use
+	retired []al.Buffer
 }
 
 func (b *buffer) Delete() {
-	al.DeleteBuffers(b.handle)
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if b.data == nil {
+		return
+	}
+	al.DeleteBuffers(append(b.retired, b.handle)...)
+	b.retired = nil
+	// Sources still rendered in software play silence
+	b.data = nil
+}
+
+func (b *buffer) Duration() float32 {
+	return float32(b.duration.Seconds())
+}
+
+func (b *buffer) SampleRate() int {
+	return b.sampleRate
+}
+
+func (b *buffer) NumberOfChannels() int {
+	return b.channels
+}
+
+func (b *buffer) Length() int {
+	return b.length
+}
+
+func (b *buffer) GetChannelData(channel int) ([]float32, error) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if b.data == nil {
+		return nil, fmt.Errorf("buffer deleted")
+	}
+	if channel < 0 || channel >= len(b.data) {
+		return nil, fmt.Errorf("invalid channel %d", channel)
+	}
+	data := make([]float32, len(b.data[channel]))
+	copy(data, b.data[channel])
+	return data, nil
+}
+
+func (b *buffer) CopyFromChannel(destination []float32, channel int, startInChannel int) error {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if b.data == nil {
+		return fmt.Errorf("buffer deleted")
+	}
+	if channel < 0 || channel >= len(b.data) {
+		return fmt.Errorf("invalid channel %d", channel)
+	}
+	if startInChannel < 0 || startInChannel > len(b.data[channel]) {
+		return fmt.Errorf("invalid start in channel %d", startInChannel)
+	}
+	copy(destination, b.data[channel][startInChannel:])
+	return nil
+}
+
+func (b *buffer) CopyToChannel(source []float32, channel int, startInChannel int) error {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if b.data == nil {
+		return fmt.Errorf("buffer deleted")
+	}
+	if channel < 0 || channel >= len(b.data) {
+		return fmt.Errorf("invalid channel %d", channel)
+	}
+	if startInChannel < 0 || startInChannel > len(b.data[channel]) {
+		return fmt.Errorf("invalid start in channel %d", startInChannel)
+	}
+	copy(b.data[channel][startInChannel:], source)
+	b.dirty = true
+	return nil
+}
+
+// upload sends samples to the AL buffer if they have changed
+func (b *buffer) upload() {
+	if !b.dirty {
+		return
+	}
+	b.dirty = false
+
+	format := al.FormatStereo16
+	if len(b.data) == 1 {
+		format = al.FormatMono16
+	}
+	// Interleave
+	samples := make([]int16, len(b.data)*len(b.data[0]))
+	for c, channel := range b.data {
+		for i, v := range channel {
+			samples[i*len(b.data)+c] = floatToInt16(float64(v))
+		}
+	}
+
+	// Clear pending error
+	al.Error()
+	b.handle.BufferData(uint32(format), int16ToBytes(samples), int32(b.sampleRate))
+	if al.Error() != 0 {
+		// Buffer is still queued on sources, replace it
+		b.retired = append(b.retired, b.handle)
+		b.handle = al.GenBuffers(1)[0]
+		b.handle.BufferData(uint32(format), int16ToBytes(samples), int32(b.sampleRate))
+	}
 }
 
 // Source
@@ -155,106 +456,772 @@ type sourceProxy struct {
 	pan       float32
 }
 
+// audibleGain returns the gain to apply on AL source, sources are muted while rendered in software by
+// master limiter
+func (s *sourceProxy) audibleGain() float32 {
+	if atomic.LoadInt32(&masterLimited) != 0 {
+		return 0
+	}
+	return s.gain
+}
+
 // BufferSourceNode
 
 type bufferSourceNode struct {
 	node
 	buffer *buffer
+	// handle is the AL buffer queued on source
+	handle       al.Buffer
+	playbackRate float32
+	detune       float32
+	onEnded      func()
+	// owner is the handle of current generation, passed to ended callback and event
+	owner *bufferSourceHandle
+	// rateChanged notifies duration and loop timers of current start that rate has changed
+	rateChanged chan bool
+	// generation is incremented each time node is recycled, pending starts and timers of
+	// previous generations are ignored
+	generation uint32
+	// Software rendering playhead, positions are in buffer frames
+	rendering      bool
+	renderStart    float64
+	renderOffset   float64
+	renderPosition float64
+	// renderFrame is the last block rendered, playhead is synchronized if blocks have been skipped
+	renderFrame     int64
+	renderEnd       float64
+	renderLoop      bool
+	renderLoopStart float64
+	renderLoopEnd   float64
 }
 
-func (n *bufferSourceNode) Start(delay, offset, duration float32, loop bool, loopStart, loopEnd float32) {
-	if delay > 0 {
-		delayDuration := time.Duration(delay * 1000000000)
-		go func() {
-			<-time.After(delayDuration)
-			if n.sources[0].connected {
-				n.sources[0].handle.SetGain(n.sources[0].gain)
-				n.sources[0].handle.SetPosition(al.Vector{n.sources[0].pan, 0, 0})
-			}
-			al.PlaySources(n.sources[0].handle)
-			n.sources[0].handle.Setf(0x1024, offset) // OFFSET
-			b := *(n.buffer)
-			durationDuration := b.duration
-			if duration > 0 {
-				durationDuration = time.Duration(duration * 1000000000)
-			}
-			<-time.After(durationDuration)
-			if loop {
-				if loopEnd == 0 {
-					loopEnd = float32(b.duration.Nanoseconds()) / 1000000000
-				}
-				for n.sources[0].handle.State() != al.Stopped {
-					al.PlaySources(n.sources[0].handle)
-					n.sources[0].handle.Setf(0x1024, loopStart) // OFFSET
-					<-time.After(time.Duration((loopEnd - loopStart) * 1000000000))
-				}
-			} else {
-				n.Stop()
-			}
-		}()
-	} else {
-		if n.sources[0].connected {
-			n.sources[0].handle.SetGain(n.sources[0].gain)
-			n.sources[0].handle.SetPosition(al.Vector{n.sources[0].pan, 0, 0})
-		}
+// bufferSourceHandle is the BufferSourceNode returned to callers, it acts on the pooled node only until
+// the node is recycled so that a handle kept after its end cannot control the next owner
+type bufferSourceHandle struct {
+	*bufferSourceNode
+	generation uint32
+}
+
+func (h *bufferSourceHandle) Start(when float64, offset, duration float32, loop bool, loopStart, loopEnd float32) {
+	h.start(h.generation, when, offset, duration, loop, loopStart, loopEnd)
+}
+
+func (h *bufferSourceHandle) Stop() {
+	h.stop(h.generation)
+}
+
+func (h *bufferSourceHandle) PlaybackRate(value float32) {
+	h.setPlaybackRate(h.generation, value)
+}
+
+func (h *bufferSourceHandle) Detune(cents float32) {
+	h.setDetune(h.generation, cents)
+}
+
+func (h *bufferSourceHandle) OnEnded(callback func()) {
+	h.setOnEnded(h.generation, callback)
+}
+
+// start plays node if it has not been recycled since given generation
+func (n *bufferSourceNode) start(generation uint32, when float64, offset, duration float32, loop bool, loopStart, loopEnd float32) {
+	if atomic.LoadUint32(&n.generation) != generation {
+		return
+	}
+	start := &scheduledStart{
+		node:       n,
+		generation: generation,
+		when:       when,
+		offset:     offset,
+		duration:   duration,
+		loop:       loop,
+		loopStart:  loopStart,
+		loopEnd:    loopEnd,
+	}
+	n.startRendering(start)
+	if when > currentTime() || clockPaused() {
+		// Deferred until resumed if suspended
+		scheduleStart(start)
+	} else if n.prepare(start) {
 		al.PlaySources(n.sources[0].handle)
-		n.sources[0].handle.Setf(0x1024, offset) // OFFSET
-		go func() {
-			b := *(n.buffer)
-			durationDuration := b.duration
-			if duration > 0 {
-				durationDuration = time.Duration(duration * 1000000000)
-			}
-			<-time.After(durationDuration)
-			if loop {
-				if loopEnd == 0 {
-					loopEnd = float32(b.duration.Nanoseconds()) / 1000000000
-				}
-				for n.sources[0].handle.State() != al.Stopped {
-					al.PlaySources(n.sources[0].handle)
-					n.sources[0].handle.Setf(0x1024, loopStart) // OFFSET
-					<-time.After(time.Duration((loopEnd - loopStart) * 1000000000))
-				}
-			} else {
-				n.Stop()
+		n.started(start)
+	}
+}
+
+// prepare sets source parameters before playing, returns false if node has been stopped meanwhile
+func (n *bufferSourceNode) prepare(start *scheduledStart) bool {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if atomic.LoadUint32(&n.generation) != start.generation || n.buffer == nil {
+		return false
+	}
+	start.bufferDuration = n.buffer.Duration()
+	source := n.sources[0].handle
+	if n.sources[0].connected {
+		source.SetGain(n.sources[0].audibleGain())
+		source.SetPosition(al.Vector{n.sources[0].pan, 0, 0})
+	}
+	source.Setf(0x1003, n.rate()) // PITCH
+	if n.loopsWholeBuffer(start) {
+		source.Seti(0x1007, 1) // LOOPING
+	}
+	source.Setf(0x1024, start.offset) // SEC_OFFSET, applied on play
+	return true
+}
+
+// loopsWholeBuffer indicates if looping can be done by AL without timers
+func (n *bufferSourceNode) loopsWholeBuffer(start *scheduledStart) bool {
+	return start.loop && start.duration <= 0 && start.loopStart <= 0 &&
+		(start.loopEnd <= 0 || start.loopEnd >= start.bufferDuration)
+}
+
+// started handles duration and loops in background from the actual start time
+func (n *bufferSourceNode) started(start *scheduledStart) {
+	if n.loopsWholeBuffer(start) {
+		return
+	}
+	startTime := start.when
+	if now := currentTime(); now > startTime {
+		startTime = now
+	}
+	wake := make(chan bool, 1)
+	graphMutex.Lock()
+	n.rateChanged = wake
+	graphMutex.Unlock()
+	go func() {
+		bufferDuration := start.bufferDuration
+		duration := start.duration
+		if duration <= 0 {
+			duration = bufferDuration - start.offset
+		}
+		// Times are computed from start time to avoid drift
+		end := n.waitSegment(start, wake, startTime+n.realTime(duration), start.offset+duration)
+		if !start.loop {
+			n.stop(start.generation)
+			return
+		}
+		loopStart, loopEnd := start.loopStart, start.loopEnd
+		if loopEnd <= 0 || loopEnd > bufferDuration {
+			loopEnd = bufferDuration
+		}
+		if loopEnd <= loopStart {
+			loopStart = 0
+		}
+		for atomic.LoadUint32(&n.generation) == start.generation {
+			source := n.sources[0].handle
+			if source.State() != al.Playing {
+				al.PlaySources(source)
 			}
-		}()
+			source.Setf(0x1024, loopStart) // SEC_OFFSET
+			end = n.waitSegment(start, wake, end+n.realTime(loopEnd-loopStart), loopEnd)
+		}
+	}()
+}
+
+// waitSegment waits until playing reaches segmentEnd (in buffer seconds) expected at end, the expected time
+// is recomputed from AL position on rate changes. Returns the time at which the segment has ended.
+func (n *bufferSourceNode) waitSegment(start *scheduledStart, wake chan bool, end float64, segmentEnd float32) float64 {
+	for !sleepUntilOrWake(end, wake) {
+		if atomic.LoadUint32(&n.generation) != start.generation {
+			return end
+		}
+		source := n.sources[0].handle
+		if state := source.State(); state == al.Playing || state == al.Paused {
+			end = currentTime() + n.realTime(segmentEnd-source.Getf(0x1024)) // SEC_OFFSET
+		}
 	}
+	return end
 }
 
-func (n *bufferSourceNode) Stop() {
+// stop stops and recycles the node if it has not been recycled since given generation
+func (n *bufferSourceNode) stop(generation uint32) {
+	if !atomic.CompareAndSwapUint32(&n.generation, generation, generation+1) {
+		return
+	}
+	cancelStart(n)
 	al.StopSources(n.sources[0].handle)
-	if n.buffer != nil {
-		b := *(n.buffer)
+	graphMutex.Lock()
+	n.rendering = false
+	n.disconnectAll()
+	recycled := n.buffer != nil
+	n.buffer = nil
+	n.rateChanged = nil
+	n.playbackRate = 1
+	n.detune = 0
+	onEnded, owner := n.onEnded, n.owner
+	n.onEnded, n.owner = nil, nil
+	graphMutex.Unlock()
+	if recycled {
 		n.sources[0].gain = 1
 		n.sources[0].pan = 0
 		n.sources[0].handle.SetGain(0)
 		n.sources[0].handle.SetPosition(al.Vector{0, 0, 0})
-		n.sources[0].handle.UnqueueBuffers(b.handle)
-		n.node.to = n.node.to[:0]
-		n.buffer = nil
+		n.sources[0].handle.Setf(0x1003, 1) // PITCH
+		n.sources[0].handle.Seti(0x1007, 0) // LOOPING
+		n.sources[0].handle.UnqueueBuffers(n.handle)
+		// Fired before recycling, so that sources created from callbacks are not this node
+		fireEnded(owner, onEnded)
 		sourcePool <- n
 	}
 }
 
-func (n *bufferSourceNode) Play(loop bool) {
+// setOnEnded sets the ended callback if node has not been recycled since given generation
+func (n *bufferSourceNode) setOnEnded(generation uint32, callback func()) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if atomic.LoadUint32(&n.generation) != generation {
+		return
+	}
+	n.onEnded = callback
+}
+
+// setPlaybackRate sets the playback rate if node has not been recycled since given generation
+func (n *bufferSourceNode) setPlaybackRate(generation uint32, value float32) {
+	if value <= 0 {
+		return
+	}
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if atomic.LoadUint32(&n.generation) != generation {
+		return
+	}
+	n.playbackRate = value
+	n.sources[0].handle.Setf(0x1003, n.rate()) // PITCH
+	n.notifyRateChanged()
+}
+
+// setDetune sets the detune if node has not been recycled since given generation
+func (n *bufferSourceNode) setDetune(generation uint32, cents float32) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if atomic.LoadUint32(&n.generation) != generation {
+		return
+	}
+	n.detune = cents
+	n.sources[0].handle.Setf(0x1003, n.rate()) // PITCH
+	n.notifyRateChanged()
+}
+
+// notifyRateChanged wakes up timers of current start, graphMutex must be held
+func (n *bufferSourceNode) notifyRateChanged() {
+	if n.rateChanged != nil {
+		select {
+		case n.rateChanged <- true:
+		default:
+		}
+	}
+}
+
+// rate returns the computed playback rate from playbackRate and detune
+func (n *bufferSourceNode) rate() float32 {
+	return n.playbackRate * float32(math.Pow(2, float64(n.detune)/1200))
+}
+
+// startRendering sets the software playhead from start settings, buffer is rendered in software only
+// when connected to software nodes
+func (n *bufferSourceNode) startRendering(start *scheduledStart) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if atomic.LoadUint32(&n.generation) != start.generation || n.buffer == nil {
+		return
+	}
+	rate := float64(n.buffer.sampleRate)
+	length := float64(n.buffer.length)
+	if length == 0 || n.buffer.data == nil {
+		return
+	}
+	n.rendering = true
+	n.renderStart = math.Max(start.when, nextRenderTime())
+	n.renderOffset = math.Min(float64(start.offset)*rate, length)
+	n.renderPosition = n.renderOffset
+	n.renderFrame = renderFrame
+	n.renderEnd = length
+	if start.duration > 0 {
+		n.renderEnd = math.Min(n.renderOffset+float64(start.duration)*rate, length)
+	}
+	n.renderLoop = start.loop
+	n.renderLoopStart = math.Min(float64(start.loopStart)*rate, length)
+	n.renderLoopEnd = length
+	if start.loopEnd > 0 {
+		n.renderLoopEnd = math.Min(float64(start.loopEnd)*rate, length)
+	}
+	if n.renderLoopEnd <= n.renderLoopStart {
+		n.renderLoopStart = 0
+	}
+}
+
+// render renders buffer from software playhead, sources played by AL have no wet output
+func (n *bufferSourceNode) render(out *renderBlock, wet bool) {
+	*out = silentBlock
+	if wet || !n.rendering || n.buffer == nil {
+		return
+	}
+	data := n.buffer.data
+	if len(data) == 0 {
+		// Buffer deleted while playing
+		n.rendering = false
+		return
+	}
+	step := float64(n.buffer.sampleRate) / renderSampleRate * float64(n.rate())
+	if n.renderFrame != renderFrame-1 {
+		// Not pulled since start (played by AL only), continue from where AL is
+		n.syncPosition(step)
+	}
+	n.renderFrame = renderFrame
+	for i := 0; i < renderBlockSize; i++ {
+		if renderTime+float64(i)/renderSampleRate < n.renderStart {
+			continue
+		}
+		if n.renderLoop {
+			for n.renderPosition >= n.renderLoopEnd {
+				n.renderPosition -= n.renderLoopEnd - n.renderLoopStart
+			}
+		} else if n.renderPosition >= n.renderEnd {
+			n.rendering = false
+			return
+		}
+		k := int(n.renderPosition)
+		frac := float32(n.renderPosition - float64(k))
+		for c := range out {
+			channel := data[c%len(data)]
+			v := channel[k]
+			if k+1 < len(channel) {
+				v += (channel[k+1] - v) * frac
+			}
+			out[c][i] = v
+		}
+		n.renderPosition += step
+	}
+}
+
+// syncPosition moves the playhead to the position reached at current render time since start
+func (n *bufferSourceNode) syncPosition(step float64) {
+	elapsed := renderTime - n.renderStart
+	if elapsed <= 0 {
+		n.renderPosition = n.renderOffset
+		return
+	}
+	position := n.renderOffset + elapsed*renderSampleRate*step
+	if n.renderLoop && position >= n.renderLoopEnd {
+		position = n.renderLoopStart + math.Mod(position-n.renderLoopStart, n.renderLoopEnd-n.renderLoopStart)
+	}
+	n.renderPosition = position
+}
+
+// realTime converts a duration in buffer seconds to seconds of audio context clock at current rate
+func (n *bufferSourceNode) realTime(seconds float32) float64 {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	return float64(seconds) / float64(n.rate())
+}
+
+// MediaElementSourceNode
+
+const (
+	// Number of AL buffers queued on streamed sources
+	streamBufferCount = 4
+	// Number of samples per channel in each streamed AL buffer
+	streamBufferSize = 8192
+	// Interval between 2 refills of streamed sources
+	streamRefillInterval = 50 * time.Millisecond
+)
+
+type mediaElementSourceNode struct {
+	node
+	mutex   sync.Mutex
+	data    []byte
+	decoder Decoder
+	reader  SampleReader
+	// free holds AL buffers not queued on source
+	free    []al.Buffer
+	buffers []al.Buffer
+	samples []int16
+	bytes   []byte
+	// positions holds the stream position of queued buffers in samples for each channel
+	positions []int64
+	// queued holds the decoded samples of queued buffers, used to start software rendering from
+	// the position played by AL
+	queued [][]int16
+	// position is the stream position of next decoded sample
+	position int64
+	// length is the stream length in samples for each channel, 0 if unknown
+	length     int64
+	sampleRate int
+	loop       bool
+	playing    bool
+	ended      bool
+	done       chan bool
+	onEnded    func()
+	// ring holds decoded samples for software rendering, one slice per channel, it is filled from
+	// queued samples once rendering has pulled it and then fed by decoding
+	ring         [][]float32
+	ringPosition float64
+	ringPulled   bool
+}
+
+func (n *mediaElementSourceNode) Play(loop bool) {
+	contextMutex.Lock()
+	if contextState == ContextSuspended {
+		// Played on resume
+		n.mutex.Lock()
+		n.loop = loop
+		n.mutex.Unlock()
+		for _, suspended := range suspendedMediaElements {
+			if suspended == n {
+				contextMutex.Unlock()
+				return
+			}
+		}
+		suspendedMediaElements = append(suspendedMediaElements, n)
+		contextMutex.Unlock()
+		return
+	}
+	contextMutex.Unlock()
+	n.play(loop)
+}
+
+// play starts or resumes playing regardless of context state
+func (n *mediaElementSourceNode) play(loop bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.done == nil {
+		return
+	}
+	n.loop = loop
+	if n.playing {
+		return
+	}
+	if n.ended {
+		if err := n.rewind(); err != nil {
+			return
+		}
+	}
+	n.refill()
 	if n.sources[0].connected {
-		n.sources[0].handle.SetGain(n.sources[0].gain)
+		n.sources[0].handle.SetGain(n.sources[0].audibleGain())
 		n.sources[0].handle.SetPosition(al.Vector{n.sources[0].pan, 0, 0})
 	}
 	al.PlaySources(n.sources[0].handle)
-	if loop {
-		n.sources[0].handle.Seti(0x1007, 1) //LOOP
-	}
+	n.playing = true
+}
 
+func (n *mediaElementSourceNode) Pause() {
+	contextMutex.Lock()
+	removeSuspendedMediaElement(n)
+	contextMutex.Unlock()
+	n.pause()
 }
 
-func (n *bufferSourceNode) Pause() {
+// pause pauses playing regardless of context state
+func (n *mediaElementSourceNode) pause() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
 	al.PauseSources(n.sources[0].handle)
+	n.playing = false
+}
+
+func (n *mediaElementSourceNode) Seek(seconds float32) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.done == nil {
+		return
+	}
+	position := int64(float64(seconds) * float64(n.sampleRate))
+	if position < 0 {
+		position = 0
+	} else if n.length > 0 && position > n.length {
+		position = n.length
+	}
+
+	// Flush queued buffers
+	source := n.sources[0].handle
+	al.StopSources(source)
+	source.Seti(0x1009, 0) // AL_BUFFER, unqueue all
+	n.free = append(n.free[:0], n.buffers...)
+	n.positions = n.positions[:0]
+	n.queued = n.queued[:0]
+	n.clearRing()
+
+	if err := n.seek(position); err != nil {
+		n.ended = true
+		n.playing = false
+		return
+	}
+	n.refill()
+	if n.playing {
+		al.PlaySources(source)
+	}
+}
+
+func (n *mediaElementSourceNode) CurrentTime() float32 {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.done == nil {
+		return 0
+	}
+	if len(n.positions) == 0 {
+		if n.length > 0 && n.position >= n.length {
+			return n.Duration()
+		}
+		return float32(float64(n.position) / float64(n.sampleRate))
+	}
+	position := float64(n.positions[0])/float64(n.sampleRate) + float64(n.sources[0].handle.Getf(0x1024)) // AL_SEC_OFFSET
+	if duration := float64(n.Duration()); duration > 0 && position > duration {
+		// Looping across stream end
+		position -= duration
+	}
+	return float32(position)
+}
+
+func (n *mediaElementSourceNode) Duration() float32 {
+	return float32(float64(n.length) / float64(n.sampleRate))
+}
+
+func (n *mediaElementSourceNode) Paused() bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return !n.playing
+}
+
+func (n *mediaElementSourceNode) OnEnded(callback func()) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.onEnded = callback
+}
+
+func (n *mediaElementSourceNode) Delete() {
+	contextMutex.Lock()
+	delete(mediaElementNodes, n)
+	removeSuspendedMediaElement(n)
+	contextMutex.Unlock()
+
+	// AL source must not be referenced by graph once deleted
+	graphMutex.Lock()
+	n.disconnectAll()
+	graphMutex.Unlock()
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.done == nil {
+		return
+	}
+	close(n.done)
+	n.done = nil
+	al.StopSources(n.sources[0].handle)
+	n.sources[0].handle.Seti(0x1009, 0) // AL_BUFFER, unqueue all
+	al.DeleteSources(n.sources[0].handle)
+	al.DeleteBuffers(n.buffers...)
+	n.free, n.buffers, n.queued = nil, nil, nil
+	n.data, n.reader = nil, nil
+}
+
+// stream refills source buffers in background until node is deleted
+func (n *mediaElementSourceNode) stream(done chan bool) {
+	ticker := time.NewTicker(streamRefillInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			n.mutex.Lock()
+			ended := false
+			if n.playing {
+				ended = n.refill()
+			}
+			onEnded := n.onEnded
+			n.mutex.Unlock()
+			if ended {
+				fireEnded(n, onEnded)
+			}
+		}
+	}
+}
+
+// refill decodes next samples into processed and free buffers and queues them on source,
+// returns true if playing has just ended
+func (n *mediaElementSourceNode) refill() bool {
+	source := n.sources[0].handle
+	unqueued := make([]al.Buffer, source.BuffersProcessed())
+	if len(unqueued) > 0 {
+		source.UnqueueBuffers(unqueued...)
+		n.free = append(n.free, unqueued...)
+		n.positions = n.positions[len(unqueued):]
+		n.queued = n.queued[len(unqueued):]
+	}
+
+	for len(n.free) > 0 && !n.ended {
+		position := n.position
+		if !n.fill(n.free[0]) {
+			break
+		}
+		source.QueueBuffers(n.free[0])
+		n.positions = append(n.positions, position)
+		n.free = n.free[1:]
+	}
+
+	if n.playing && source.State() != al.Playing {
+		if source.BuffersQueued() > 0 {
+			// Buffer underrun, restart
+			al.PlaySources(source)
+		} else if n.ended {
+			n.playing = false
+			return true
+		}
+	}
+	return false
+}
+
+// fill decodes next samples into buffer, returns false if no more samples are available
+func (n *mediaElementSourceNode) fill(buffer al.Buffer) bool {
+	count := 0
+	// rewound is set until samples are read after a rewind, empty streams are not looped forever
+	rewound := false
+	for count < len(n.samples) {
+		read, err := n.reader.Read(n.samples[count:])
+		count += read
+		n.position += int64(read / n.reader.Channels())
+		if read > 0 {
+			rewound = false
+		}
+		if err != nil {
+			// End of stream or decoding error
+			if n.loop && err == io.EOF && !rewound {
+				if n.rewind() == nil {
+					rewound = true
+					continue
+				}
+			}
+			n.ended = true
+			break
+		}
+	}
+	if count == 0 {
+		return false
+	}
+	format := al.FormatStereo16
+	if n.reader.Channels() == 1 {
+		format = al.FormatMono16
+	}
+	n.queued = append(n.queued, append([]int16(nil), n.samples[:count]...))
+	if n.ringPulled {
+		n.feedRing(n.samples[:count])
+	}
+	n.bytes = putInt16Bytes(n.bytes, n.samples[:count])
+	buffer.BufferData(uint32(format), n.bytes, int32(n.reader.SampleRate()))
+	return true
+}
+
+// feedRing appends decoded samples to ring, ring is dropped if not consumed
+func (n *mediaElementSourceNode) feedRing(samples []int16) {
+	channels := len(n.ring)
+	if len(n.ring[0])-int(n.ringPosition) > streamBufferCount*streamBufferSize {
+		n.clearRing()
+		n.ringPulled = false
+		return
+	}
+	for i := 0; i+channels <= len(samples); i += channels {
+		for c := range n.ring {
+			n.ring[c] = append(n.ring[c], float32(samples[i+c])/32768)
+		}
+	}
+}
+
+// primeRing fills ring with queued samples from the position played by AL, so that software rendering
+// continues what is heard
+func (n *mediaElementSourceNode) primeRing() {
+	n.clearRing()
+	for _, samples := range n.queued {
+		n.feedRing(samples)
+	}
+	if len(n.queued) > 0 {
+		n.ringPosition = float64(n.sources[0].handle.Getf(0x1024)) * float64(n.sampleRate) // AL_SEC_OFFSET
+		if length := float64(len(n.ring[0])); n.ringPosition > length {
+			n.ringPosition = length
+		}
+	}
+}
+
+func (n *mediaElementSourceNode) clearRing() {
+	for c := range n.ring {
+		n.ring[c] = n.ring[c][:0]
+	}
+	n.ringPosition = 0
+}
+
+// render renders decoded samples from ring, sources played by AL have no wet output
+func (n *mediaElementSourceNode) render(out *renderBlock, wet bool) {
+	*out = silentBlock
+	if wet {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.done == nil {
+		return
+	}
+	if !n.ringPulled {
+		n.primeRing()
+		n.ringPulled = true
+	}
+	if !n.playing {
+		return
+	}
+	step := float64(n.sampleRate) / renderSampleRate
+	length := len(n.ring[0])
+	for i := 0; i < renderBlockSize; i++ {
+		k := int(n.ringPosition)
+		if k+1 >= length {
+			break
+		}
+		frac := float32(n.ringPosition - float64(k))
+		for c := range out {
+			channel := n.ring[c%len(n.ring)]
+			out[c][i] = channel[k] + (channel[k+1]-channel[k])*frac
+		}
+		n.ringPosition += step
+	}
+	// Drop consumed samples once in a while
+	if consumed := int(n.ringPosition); consumed >= streamBufferSize {
+		for c := range n.ring {
+			n.ring[c] = append(n.ring[c][:0], n.ring[c][consumed:]...)
+		}
+		n.ringPosition -= float64(consumed)
+	}
+}
+
+// rewind restarts decoding from the beginning of the stream
+func (n *mediaElementSourceNode) rewind() error {
+	return n.seek(0)
 }
 
-func (n *bufferSourceNode) Delete() {
-	al.DeleteBuffers(n.buffer.handle)
+// seek sets the position of next decoded sample
+func (n *mediaElementSourceNode) seek(position int64) error {
+	if seeker, ok := n.reader.(SampleSeeker); ok {
+		if err := seeker.SetPosition(position); err != nil {
+			return err
+		}
+	} else {
+		// No random access, decode from beginning and skip samples
+		reader, err := n.decoder.Decode(n.data)
+		if err != nil {
+			return err
+		}
+		n.reader = reader
+		for skip := position * int64(reader.Channels()); skip > 0; {
+			chunk := n.samples
+			if int64(len(chunk)) > skip {
+				chunk = chunk[:skip]
+			}
+			read, err := reader.Read(chunk)
+			skip -= int64(read)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	n.position = position
+	n.ended = false
+	return nil
 }
 
 // DestinationNode
@@ -268,7 +1235,7 @@ func (n *destinationNode) onConnectStateChanged(connected bool, sources []*sourc
 		if connected {
 			source.connected = true
 			if source.handle.State() == al.Playing {
-				source.handle.SetGain(source.gain)
+				source.handle.SetGain(source.audibleGain())
 			}
 		} else {
 			source.connected = false
@@ -281,26 +1248,66 @@ func (n *destinationNode) onConnectStateChanged(connected bool, sources []*sourc
 
 type stereoPannerNode struct {
 	node
-	pan float32
+	pan *audioParam
+	// position is the applied AL position from pan
+	position float32
 }
 
 func (n *stereoPannerNode) Pan(value float32) {
+	n.pan.SetValue(value)
+}
+
+func (n *stereoPannerNode) PanParam() AudioParam {
+	return n.pan
+}
+
+func (n *stereoPannerNode) applyPan(value float32) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
 	if math.Abs(float64(value)) > 0.7 {
-		n.pan = value
+		n.position = value
 	} else {
-		n.pan = 0
+		n.position = 0
 	}
 	for _, source := range n.sources {
-		source.pan = n.pan
+		source.pan = n.position
 		if source.connected {
-			source.handle.SetPosition(al.Vector{n.pan, 0, 0})
+			source.handle.SetPosition(al.Vector{n.position, 0, 0})
+		}
+	}
+}
+
+// render applies equal-power panning to inputs in software
+func (n *stereoPannerNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(out, wet)
+	pans := n.pan.renderValues()
+	var pan float64
+	var gainL, gainR float32
+	for i := range out[0] {
+		// Gains change only if pan is modulated
+		if i == 0 || pans[i] != pans[i-1] {
+			pan = float64(pans[i])
+			x := pan
+			if pan <= 0 {
+				x = pan + 1
+			}
+			gainL = float32(math.Cos(x * math.Pi / 2))
+			gainR = float32(math.Sin(x * math.Pi / 2))
+		}
+		l, r := out[0][i], out[1][i]
+		if pan <= 0 {
+			out[0][i] = l + r*gainL
+			out[1][i] = r * gainR
+		} else {
+			out[0][i] = l * gainL
+			out[1][i] = r + l*gainR
 		}
 	}
 }
 
 func (n *stereoPannerNode) onConnectStateChanged(connected bool, sources []*sourceProxy) {
 	for _, source := range sources {
-		source.pan = n.pan
+		source.pan = n.position
 	}
 	n.node.onConnectStateChanged(connected, sources)
 }
@@ -309,74 +1316,154 @@ func (n *stereoPannerNode) onConnectStateChanged(connected bool, sources []*sour
 
 type gainNode struct {
 	node
-	gain float32
+	gain *audioParam
 }
 
 func (n *gainNode) Gain(value float32) {
-	n.gain = value
+	n.gain.SetValue(value)
+}
+
+func (n *gainNode) GainParam() AudioParam {
+	return n.gain
+}
+
+func (n *gainNode) applyGain(value float32) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
 	for _, source := range n.sources {
-		source.gain = n.gain
+		source.gain = value
 		if source.connected {
-			source.handle.SetGain(source.gain)
+			source.handle.SetGain(source.audibleGain())
+		}
+	}
+}
+
+// render applies gain to inputs in software
+func (n *gainNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(out, wet)
+	gains := n.gain.renderValues()
+	for c := range out {
+		for i := range out[c] {
+			out[c][i] *= gains[i]
 		}
 	}
 }
 
 func (n *gainNode) onConnectStateChanged(connected bool, sources []*sourceProxy) {
+	gain := n.gain.Value()
 	for _, source := range sources {
-		source.gain = n.gain
+		source.gain = gain
 	}
 	n.node.onConnectStateChanged(connected, sources)
 }
 
 // Factories
 
+// setupSource initializes AL source parameters for graph usage, sources are muted until connected
+func setupSource(source al.Source) {
+	source.SetMaxGain(1.0)
+	source.SetGain(0)
+	source.Setf(0x202, 1)    //AL_SOURCE_RELATIVE
+	source.Setf(0x1023, 1.5) //AL_MAX_DISTANCE
+	source.Setf(0x1020, 0.5) //AL_REFERENCE_DISTANCE
+}
+
 func createBuffer(path string) (Buffer, error) {
-	alBuffer, duration, err := alBufferFromPath(path)
-	if err != nil {
-		return nil, err
-	}
+	return bufferFromPath(path)
+}
 
-	outBuffer := buffer{
-		handle:   alBuffer,
-		duration: duration,
-	}
+func createBufferFromBytes(data []byte, format string) (Buffer, error) {
+	return bufferFromBytes(data, format)
+}
 
-	return &outBuffer, nil
+func createBufferFromPCM(channels [][]float32, sampleRate int) (Buffer, error) {
+	data := make([][]float32, len(channels))
+	for c, channel := range channels {
+		data[c] = make([]float32, len(channel))
+		copy(data[c], channel)
+	}
+	return newBuffer(data, sampleRate)
 }
 
 func createBufferSourceNode(b Buffer) (BufferSourceNode, error) {
 	audioBuffer := b.(*buffer)
+	graphMutex.Lock()
+	if audioBuffer.data == nil {
+		graphMutex.Unlock()
+		return nil, fmt.Errorf("buffer deleted")
+	}
+	audioBuffer.upload()
+	handle := audioBuffer.handle
+	graphMutex.Unlock()
 	bufferSource := <-sourcePool
+	owner := &bufferSourceHandle{
+		bufferSourceNode: bufferSource,
+		generation:       atomic.LoadUint32(&bufferSource.generation),
+	}
+	graphMutex.Lock()
 	bufferSource.buffer = audioBuffer
-	bufferSource.node.sources[0].handle.QueueBuffers(audioBuffer.handle)
-	return bufferSource, nil
+	bufferSource.owner = owner
+	graphMutex.Unlock()
+	bufferSource.handle = handle
+	bufferSource.node.sources[0].handle.QueueBuffers(handle)
+	return owner, nil
 }
 
-func createMediaElementSourceNodePath(path string) (MediaElementSourceNode, error) {
-	alBuffer, duration, err := alBufferFromPath(path)
+func createMediaElementSourceNode(path string) (MediaElementSourceNode, error) {
+	// Read
+	data, err := _pluginInstance.runtime.GetAsset(path)
 	if err != nil {
 		return nil, err
 	}
+	// Detect format
+	format, err := sniffDecoderFormat(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	reader, err := format.decoder.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if reader.Channels() < 1 || reader.Channels() > 2 {
+		return nil, fmt.Errorf("%s: unsupported number of channels %d", path, reader.Channels())
+	}
+
+	source := al.GenSources(1)[0]
+	setupSource(source)
 
-	audioBuffer := buffer{
-		handle:   alBuffer,
-		duration: duration,
+	mediaElementSource := &mediaElementSourceNode{
+		node: node{
+			sources: []*sourceProxy{&sourceProxy{
+				handle:    source,
+				connected: false,
+				gain:      1,
+				pan:       0,
+			}},
+			to: make([]connectListener, 0, 1),
+		},
+		data:       data,
+		decoder:    format.decoder,
+		reader:     reader,
+		positions:  make([]int64, 0, streamBufferCount),
+		sampleRate: reader.SampleRate(),
+		buffers:    al.GenBuffers(streamBufferCount),
+		samples:    make([]int16, streamBufferSize*reader.Channels()),
+		done:       make(chan bool),
+	}
+	mediaElementSource.free = append(make([]al.Buffer, 0, streamBufferCount), mediaElementSource.buffers...)
+	mediaElementSource.ring = make([][]float32, reader.Channels())
+	mediaElementSource.process = mediaElementSource.render
+	if seeker, ok := reader.(SampleSeeker); ok {
+		mediaElementSource.length = seeker.Length()
 	}
 
-	return createMediaElementSourceNodeBuffer(&audioBuffer)
-}
+	contextMutex.Lock()
+	mediaElementNodes[mediaElementSource] = true
+	contextMutex.Unlock()
 
-func createMediaElementSourceNodeBuffer(b Buffer) (MediaElementSourceNode, error) {
-	audioBuffer := b.(*buffer)
-	bufferSource := <-sourcePool
-	bufferSource.buffer = audioBuffer
-	bufferSource.node.sources[0].handle.QueueBuffers(audioBuffer.handle)
-	return bufferSource, nil
-}
+	go mediaElementSource.stream(mediaElementSource.done)
 
-func createMediaElementSourceNode(path string) (MediaElementSourceNode, error) {
-	return createMediaElementSourceNodePath(path)
+	return mediaElementSource, nil
 }
 
 func createDestinationNode() (DestinationNode, error) {
@@ -384,23 +1471,27 @@ func createDestinationNode() (DestinationNode, error) {
 }
 
 func createStereoPannerNode() (StereoPannerNode, error) {
-	return &stereoPannerNode{
-		pan: 0,
+	stereoPanner := &stereoPannerNode{
 		node: node{
 			sources: make([]*sourceProxy, 0, sourcePoolSize),
 			to:      make([]connectListener, 0, 1),
 		},
-	}, nil
+	}
+	stereoPanner.pan = newAudioParam(0, -1, 1, stereoPanner.applyPan)
+	stereoPanner.process = stereoPanner.render
+	return stereoPanner, nil
 }
 
 func createGainNode() (GainNode, error) {
-	return &gainNode{
-		gain: 1,
+	gain := &gainNode{
 		node: node{
 			sources: make([]*sourceProxy, 0, sourcePoolSize),
 			to:      make([]connectListener, 0, 1),
 		},
-	}, nil
+	}
+	gain.gain = newAudioParam(1, 0, math.MaxFloat32, gain.applyGain)
+	gain.process = gain.render
+	return gain, nil
 }
 
 // -------------------------------------------------------------------- //
@@ -422,7 +1513,15 @@ func getByteArrayBuffer(size int) []byte {
 }
 
 func int16ToBytes(values []int16) []byte {
-	b := getByteArrayBuffer(2 * len(values))
+	return putInt16Bytes(getByteArrayBuffer(2*len(values)), values)
+}
+
+// putInt16Bytes converts values in b using native endianness, b is extended if needed
+func putInt16Bytes(b []byte, values []int16) []byte {
+	if cap(b) < 2*len(values) {
+		b = make([]byte, 2*len(values))
+	}
+	b = b[:2*len(values)]
 	if nativeEndian == binary.LittleEndian {
 		for i, v := range values {
 			u := *(*int16)(unsafe.Pointer(&v))
@@ -439,32 +1538,74 @@ func int16ToBytes(values []int16) []byte {
 	return b
 }
 
-func alBufferFromPath(path string) (al.Buffer, time.Duration, error) {
-	fileExt := filepath.Ext(path)
-	// Read, Decode and upload data
-	switch fileExt {
-	case ".ogg":
-		// Read
-		inData, err := _pluginInstance.runtime.GetAsset(path)
-		if err != nil {
-			return 0, 0, err
-		}
-		// Decode
-		data, channels, sampleRate, err := vorbis.Decode(inData)
-		if err != nil {
-			return 0, 0, err
-		}
-		// Gen AL buffer
-		alBuffer := al.GenBuffers(1)[0]
-		format := al.FormatStereo16
-		if channels == 1 {
-			format = al.FormatMono16
+func bufferFromPath(path string) (*buffer, error) {
+	// Read
+	inData, err := _pluginInstance.runtime.GetAsset(path)
+	if err != nil {
+		return nil, err
+	}
+	audioBuffer, err := bufferFromBytes(inData, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return audioBuffer, nil
+}
+
+func bufferFromBytes(inData []byte, formatName string) (*buffer, error) {
+	// Detect format
+	var format *decoderFormat
+	var err error
+	if formatName != "" {
+		format, err = findDecoderFormat(formatName)
+	} else {
+		format, err = sniffDecoderFormat(inData)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Decode
+	reader, err := format.decoder.Decode(inData)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readAllSamples(reader)
+	if err != nil {
+		return nil, err
+	}
+	// Upload
+	return bufferFromPCM(data, reader.Channels(), reader.SampleRate())
+}
+
+func bufferFromPCM(samples []int16, channels int, sampleRate int) (*buffer, error) {
+	if channels < 1 {
+		return nil, fmt.Errorf("unsupported number of channels %d", channels)
+	}
+	// Deinterleave
+	length := len(samples) / channels
+	data := make([][]float32, channels)
+	for c := range data {
+		data[c] = make([]float32, length)
+		for i := range data[c] {
+			data[c][i] = float32(samples[i*channels+c]) / 32768
 		}
-		// Upload
-		alBuffer.BufferData(uint32(format), int16ToBytes(data), int32(sampleRate))
+	}
+	return newBuffer(data, sampleRate)
+}
 
-		return alBuffer, time.Duration((float32(len(data)) / float32(channels) / float32(sampleRate)) * 1000000000), nil
-	default:
-		return 0, 0, fmt.Errorf("audio extension not supported %s", fileExt)
+// newBuffer creates a buffer from planar samples and uploads them in a new AL buffer
+func newBuffer(data [][]float32, sampleRate int) (*buffer, error) {
+	if len(data) < 1 || len(data) > 2 {
+		return nil, fmt.Errorf("unsupported number of channels %d", len(data))
+	}
+	audioBuffer := &buffer{
+		handle:     al.GenBuffers(1)[0],
+		duration:   time.Duration(int64(len(data[0])) * int64(time.Second) / int64(sampleRate)),
+		sampleRate: sampleRate,
+		channels:   len(data),
+		length:     len(data[0]),
+		data:       data,
+		dirty:      true,
 	}
+	audioBuffer.upload()
+	return audioBuffer, nil
 }
@@ -5,7 +5,9 @@
 package audio
 
 import (
+	"encoding/binary"
 	fmt "fmt"
+	"math"
 	js "syscall/js"
 
 	tge "github.com/thommil/tge"
@@ -13,11 +15,23 @@ import (
 
 type plugin struct {
 	isInit   bool
+	closed   bool
+	runtime  tge.Runtime
 	jsTge    *js.Value
 	audioCtx *js.Value
+	// masterInput is connected to destination through master limiter if enabled
+	masterInput   *js.Value
+	masterLimiter *js.Value
+	// visibilityFunc suspends audio while page is hidden
+	visibilityFunc js.Func
+	// unlocked indicates that audio has been allowed by a user interaction
+	unlocked bool
+	// pendingPlays holds Start() and Play() calls issued before unlock
+	pendingPlays []pendingPlay
 }
 
 func (p *plugin) Init(runtime tge.Runtime) error {
+	p.runtime = runtime
 	switch host := runtime.GetHost().(type) {
 	case *js.Value:
 		jsTge := host.Get("tge")
@@ -28,18 +42,108 @@ func (p *plugin) Init(runtime tge.Runtime) error {
 	default:
 		return fmt.Errorf("Runtime host must be a *syscall/js.Value")
 	}
+	runtime.Subscribe(tge.MouseEvent{}.Channel(), p.onUserEvent)
+	runtime.Subscribe(tge.KeyEvent{}.Channel(), p.onUserEvent)
+	runtime.Subscribe(lifecyclePauseChannel, onLifecycleEvent)
+	runtime.Subscribe(lifecycleResumeChannel, onLifecycleEvent)
 	return nil
 }
 
 func (p *plugin) Dispose() {
+	if p.runtime != nil {
+		p.runtime.Unsubscribe(tge.MouseEvent{}.Channel(), p.onUserEvent)
+		p.runtime.Unsubscribe(tge.KeyEvent{}.Channel(), p.onUserEvent)
+		p.runtime.Unsubscribe(lifecyclePauseChannel, onLifecycleEvent)
+		p.runtime.Unsubscribe(lifecycleResumeChannel, onLifecycleEvent)
+	}
+	p.pendingPlays = nil
+	p.unlocked = false
+	if p.isInit {
+		js.Global().Get("document").Call("removeEventListener", "visibilitychange", p.visibilityFunc)
+		p.visibilityFunc.Release()
+		p.audioCtx.Call("close")
+		p.closed = true
+	}
 	p.isInit = false
 	p.audioCtx = nil
+	p.masterInput = nil
+	p.masterLimiter = nil
 }
 
 // -------------------------------------------------------------------- //
 // Implementation
 // -------------------------------------------------------------------- //
 
+// Unlock
+
+// pendingPlay is a play call of a node deferred until unlock
+type pendingPlay struct {
+	node Node
+	play func()
+}
+
+// onUserEvent unlocks audio on first click, touch or key press
+func (p *plugin) onUserEvent(event tge.Event) bool {
+	if p.unlocked {
+		return false
+	}
+	switch e := event.(type) {
+	case tge.MouseEvent:
+		if e.Type == tge.TypeMove {
+			return false
+		}
+	}
+	unlock()
+	return false
+}
+
+func unlock() error {
+	if !_pluginInstance.isInit {
+		if _pluginInstance.closed {
+			return fmt.Errorf("audio context is closed")
+		}
+		if err := createContext(); err != nil {
+			return err
+		}
+	}
+	if _pluginInstance.unlocked {
+		return nil
+	}
+	_pluginInstance.unlocked = true
+	_pluginInstance.audioCtx.Call("resume")
+	pendingPlays := _pluginInstance.pendingPlays
+	_pluginInstance.pendingPlays = nil
+	for _, pending := range pendingPlays {
+		pending.play()
+	}
+	return nil
+}
+
+func unlocked() bool {
+	return _pluginInstance.unlocked
+}
+
+// deferPlay queues play until unlock if needed, returns true if queued
+func deferPlay(n Node, play func()) bool {
+	if _pluginInstance.unlocked {
+		return false
+	}
+	cancelPlay(n)
+	_pluginInstance.pendingPlays = append(_pluginInstance.pendingPlays, pendingPlay{node: n, play: play})
+	return true
+}
+
+// cancelPlay removes a play of node queued until unlock, returns true if removed
+func cancelPlay(n Node) bool {
+	for i, pending := range _pluginInstance.pendingPlays {
+		if pending.node == n {
+			_pluginInstance.pendingPlays = append(_pluginInstance.pendingPlays[:i], _pluginInstance.pendingPlays[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 type buffer struct {
 	value *js.Value
 }
@@ -48,6 +152,55 @@ func (b *buffer) Delete() {
 	b.value = nil
 }
 
+func (b *buffer) Duration() float32 {
+	return float32(b.value.Get("duration").Float())
+}
+
+func (b *buffer) SampleRate() int {
+	return int(b.value.Get("sampleRate").Float())
+}
+
+func (b *buffer) NumberOfChannels() int {
+	return b.value.Get("numberOfChannels").Int()
+}
+
+func (b *buffer) Length() int {
+	return b.value.Get("length").Int()
+}
+
+func (b *buffer) GetChannelData(channel int) ([]float32, error) {
+	if channel < 0 || channel >= b.NumberOfChannels() {
+		return nil, fmt.Errorf("invalid channel %d", channel)
+	}
+	return jsToFloat32Slice(b.value.Call("getChannelData", channel)), nil
+}
+
+func (b *buffer) CopyFromChannel(destination []float32, channel int, startInChannel int) error {
+	if channel < 0 || channel >= b.NumberOfChannels() {
+		return fmt.Errorf("invalid channel %d", channel)
+	}
+	if startInChannel < 0 || startInChannel > b.Length() {
+		return fmt.Errorf("invalid start in channel %d", startInChannel)
+	}
+	jsData := b.value.Call("getChannelData", channel).Call("subarray", startInChannel, startInChannel+len(destination))
+	copy(destination, jsToFloat32Slice(jsData))
+	return nil
+}
+
+func (b *buffer) CopyToChannel(source []float32, channel int, startInChannel int) error {
+	if channel < 0 || channel >= b.NumberOfChannels() {
+		return fmt.Errorf("invalid channel %d", channel)
+	}
+	if startInChannel < 0 || startInChannel > b.Length() {
+		return fmt.Errorf("invalid start in channel %d", startInChannel)
+	}
+	if remaining := b.Length() - startInChannel; len(source) > remaining {
+		source = source[:remaining]
+	}
+	b.value.Call("copyToChannel", float32SliceToJS(source), channel, startInChannel)
+	return nil
+}
+
 type node struct {
 	value *js.Value
 }
@@ -64,6 +217,22 @@ func (n *node) Connect(to Node) Node {
 		n.value.Call("connect", *(to.(*stereoPannerNode).value))
 	case *gainNode:
 		n.value.Call("connect", *(to.(*gainNode).value))
+	case *biquadFilterNode:
+		n.value.Call("connect", *(to.(*biquadFilterNode).value))
+	case *convolverNode:
+		n.value.Call("connect", *(to.(*convolverNode).value))
+	case *delayNode:
+		n.value.Call("connect", *(to.(*delayNode).value))
+	case *dynamicsCompressorNode:
+		n.value.Call("connect", *(to.(*dynamicsCompressorNode).value))
+	case *waveShaperNode:
+		n.value.Call("connect", *(to.(*waveShaperNode).value))
+	case *oscillatorNode:
+		n.value.Call("connect", *(to.(*oscillatorNode).value))
+	case *constantSourceNode:
+		n.value.Call("connect", *(to.(*constantSourceNode).value))
+	case *analyserNode:
+		n.value.Call("connect", *(to.(*analyserNode).value))
 	}
 	return to
 }
@@ -80,14 +249,86 @@ func (n *node) Disconnect(to Node) {
 		n.value.Call("disconnect", *(to.(*stereoPannerNode).value))
 	case *gainNode:
 		n.value.Call("disconnect", *(to.(*gainNode).value))
+	case *biquadFilterNode:
+		n.value.Call("disconnect", *(to.(*biquadFilterNode).value))
+	case *convolverNode:
+		n.value.Call("disconnect", *(to.(*convolverNode).value))
+	case *delayNode:
+		n.value.Call("disconnect", *(to.(*delayNode).value))
+	case *dynamicsCompressorNode:
+		n.value.Call("disconnect", *(to.(*dynamicsCompressorNode).value))
+	case *waveShaperNode:
+		n.value.Call("disconnect", *(to.(*waveShaperNode).value))
+	case *oscillatorNode:
+		n.value.Call("disconnect", *(to.(*oscillatorNode).value))
+	case *constantSourceNode:
+		n.value.Call("disconnect", *(to.(*constantSourceNode).value))
+	case *analyserNode:
+		n.value.Call("disconnect", *(to.(*analyserNode).value))
 	}
 }
 
+func (n *node) ConnectParam(param AudioParam) {
+	n.value.Call("connect", param.(*audioParam).value)
+}
+
+func (n *node) DisconnectParam(param AudioParam) {
+	n.value.Call("disconnect", param.(*audioParam).value)
+}
+
+type audioParam struct {
+	value js.Value
+}
+
+func (p *audioParam) Value() float32 {
+	return float32(p.value.Get("value").Float())
+}
+
+func (p *audioParam) SetValue(value float32) {
+	p.value.Set("value", value)
+}
+
+func (p *audioParam) SetValueAtTime(value float32, startTime float64) {
+	p.value.Call("setValueAtTime", value, startTime)
+}
+
+func (p *audioParam) LinearRampToValueAtTime(value float32, endTime float64) {
+	p.value.Call("linearRampToValueAtTime", value, endTime)
+}
+
+func (p *audioParam) ExponentialRampToValueAtTime(value float32, endTime float64) {
+	// Zero value raises a RangeError
+	if value == 0 {
+		value = exponentialRampMinValue
+	}
+	p.value.Call("exponentialRampToValueAtTime", value, endTime)
+}
+
+func (p *audioParam) SetTargetAtTime(target float32, startTime float64, timeConstant float32) {
+	p.value.Call("setTargetAtTime", target, startTime, timeConstant)
+}
+
+func (p *audioParam) SetValueCurveAtTime(values []float32, startTime float64, duration float32) {
+	if len(values) < 2 || duration <= 0 {
+		return
+	}
+	p.value.Call("setValueCurveAtTime", float32SliceToJS(values), startTime, duration)
+}
+
+func (p *audioParam) CancelScheduledValues(startTime float64) {
+	p.value.Call("cancelScheduledValues", startTime)
+}
+
 type bufferSourceNode struct {
 	node
+	onEnded   func()
+	endedFunc js.Func
 }
 
-func (n *bufferSourceNode) Start(delay, offset, duration float32, loop bool, loopStart, loopEnd float32) {
+func (n *bufferSourceNode) Start(when float64, offset, duration float32, loop bool, loopStart, loopEnd float32) {
+	if deferPlay(n, func() { n.Start(when, offset, duration, loop, loopStart, loopEnd) }) {
+		return
+	}
 	if loop {
 		n.value.Set("loop", loop)
 		n.value.Set("loopStart", loopStart)
@@ -97,34 +338,177 @@ func (n *bufferSourceNode) Start(delay, offset, duration float32, loop bool, loo
 	}
 
 	if duration > 0 {
-		n.value.Call("start", delay, offset, duration)
+		n.value.Call("start", when, offset, duration)
 	} else {
-		n.value.Call("start", delay, offset)
+		n.value.Call("start", when, offset)
 	}
 }
 
 func (n *bufferSourceNode) Stop() {
+	if cancelPlay(n) {
+		// Never started
+		go fireEnded(n, n.onEnded)
+		return
+	}
 	n.value.Call("stop")
 }
 
+func (n *bufferSourceNode) OnEnded(callback func()) {
+	n.onEnded = callback
+}
+
+func (n *bufferSourceNode) PlaybackRate(value float32) {
+	n.value.Get("playbackRate").Set("value", value)
+}
+
+func (n *bufferSourceNode) Detune(cents float32) {
+	n.value.Get("detune").Set("value", cents)
+}
+
+// scheduledSourceNode is embedded by sources started and stopped at given times
+type scheduledSourceNode struct {
+	node
+	// source is the embedding node, passed to ended callback and event
+	source    Node
+	started   bool
+	onEnded   func()
+	endedFunc js.Func
+}
+
+func (n *scheduledSourceNode) Start(when float64) {
+	if n.started || deferPlay(n.source, func() { n.Start(when) }) {
+		return
+	}
+	n.started = true
+	n.value.Call("start", when)
+}
+
+func (n *scheduledSourceNode) Stop(when float64) {
+	if cancelPlay(n.source) {
+		// Never started
+		go fireEnded(n.source, n.onEnded)
+		return
+	}
+	if n.started {
+		n.value.Call("stop", when)
+	}
+}
+
+func (n *scheduledSourceNode) OnEnded(callback func()) {
+	n.onEnded = callback
+}
+
+// listenEnded fires ended callback and event when JS node has ended
+func (n *scheduledSourceNode) listenEnded() {
+	n.endedFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		// Sources can only be played once
+		n.endedFunc.Release()
+		go fireEnded(n.source, n.onEnded)
+		return nil
+	})
+	n.value.Set("onended", n.endedFunc)
+}
+
+type oscillatorNode struct {
+	scheduledSourceNode
+}
+
+func (n *oscillatorNode) Type(oscillatorType OscillatorType) {
+	if oscillatorType < OscillatorSine || oscillatorType >= OscillatorCustom {
+		return
+	}
+	n.value.Set("type", oscillatorType.String())
+}
+
+func (n *oscillatorNode) Frequency(value float32) {
+	n.value.Get("frequency").Set("value", value)
+}
+
+func (n *oscillatorNode) FrequencyParam() AudioParam {
+	return &audioParam{value: n.value.Get("frequency")}
+}
+
+func (n *oscillatorNode) Detune(cents float32) {
+	n.value.Get("detune").Set("value", cents)
+}
+
+func (n *oscillatorNode) DetuneParam() AudioParam {
+	return &audioParam{value: n.value.Get("detune")}
+}
+
+func (n *oscillatorNode) SetPeriodicWave(real, imag []float32) error {
+	if len(real) != len(imag) || len(real) < 2 {
+		return fmt.Errorf("invalid periodic wave coefficients (%d, %d)", len(real), len(imag))
+	}
+	wave := _pluginInstance.audioCtx.Call("createPeriodicWave", float32SliceToJS(real), float32SliceToJS(imag))
+	n.value.Call("setPeriodicWave", wave)
+	return nil
+}
+
+type constantSourceNode struct {
+	scheduledSourceNode
+}
+
+func (n *constantSourceNode) Offset(value float32) {
+	n.value.Get("offset").Set("value", value)
+}
+
+func (n *constantSourceNode) OffsetParam() AudioParam {
+	return &audioParam{value: n.value.Get("offset")}
+}
+
 type mediaElementSourceNode struct {
 	node
 	htmlElement *js.Value
+	onEnded     func()
+	endedFunc   js.Func
 }
 
 func (n *mediaElementSourceNode) Delete() {
+	cancelPlay(n)
+	n.htmlElement.Call("removeEventListener", "ended", n.endedFunc)
+	n.endedFunc.Release()
 	n.htmlElement.Call("remove")
 }
 
+func (n *mediaElementSourceNode) OnEnded(callback func()) {
+	n.onEnded = callback
+}
+
 func (n *mediaElementSourceNode) Play(loop bool) {
+	if deferPlay(n, func() { n.Play(loop) }) {
+		return
+	}
 	n.htmlElement.Set("loop", loop)
 	n.htmlElement.Call("play")
 }
 
 func (n *mediaElementSourceNode) Pause() {
+	cancelPlay(n)
 	n.htmlElement.Call("pause")
 }
 
+func (n *mediaElementSourceNode) Seek(seconds float32) {
+	n.htmlElement.Set("currentTime", seconds)
+}
+
+func (n *mediaElementSourceNode) CurrentTime() float32 {
+	return float32(n.htmlElement.Get("currentTime").Float())
+}
+
+func (n *mediaElementSourceNode) Duration() float32 {
+	duration := n.htmlElement.Get("duration").Float()
+	// NaN until metadata are loaded, +Inf for live streams
+	if math.IsNaN(duration) || math.IsInf(duration, 0) {
+		return 0
+	}
+	return float32(duration)
+}
+
+func (n *mediaElementSourceNode) Paused() bool {
+	return n.htmlElement.Get("paused").Bool()
+}
+
 type destinationNode struct {
 	node
 }
@@ -137,6 +521,10 @@ func (n *stereoPannerNode) Pan(value float32) {
 	n.value.Get("pan").Set("value", value)
 }
 
+func (n *stereoPannerNode) PanParam() AudioParam {
+	return &audioParam{value: n.value.Get("pan")}
+}
+
 type gainNode struct {
 	node
 }
@@ -145,6 +533,205 @@ func (n *gainNode) Gain(value float32) {
 	n.value.Get("gain").Set("value", value)
 }
 
+func (n *gainNode) GainParam() AudioParam {
+	return &audioParam{value: n.value.Get("gain")}
+}
+
+type biquadFilterNode struct {
+	node
+}
+
+func (n *biquadFilterNode) Type(filterType BiquadFilterType) {
+	n.value.Set("type", filterType.String())
+}
+
+func (n *biquadFilterNode) Frequency(value float32) {
+	n.value.Get("frequency").Set("value", value)
+}
+
+func (n *biquadFilterNode) FrequencyParam() AudioParam {
+	return &audioParam{value: n.value.Get("frequency")}
+}
+
+func (n *biquadFilterNode) Q(value float32) {
+	n.value.Get("Q").Set("value", value)
+}
+
+func (n *biquadFilterNode) QParam() AudioParam {
+	return &audioParam{value: n.value.Get("Q")}
+}
+
+func (n *biquadFilterNode) Gain(value float32) {
+	n.value.Get("gain").Set("value", value)
+}
+
+func (n *biquadFilterNode) GainParam() AudioParam {
+	return &audioParam{value: n.value.Get("gain")}
+}
+
+func (n *biquadFilterNode) Detune(cents float32) {
+	n.value.Get("detune").Set("value", cents)
+}
+
+func (n *biquadFilterNode) DetuneParam() AudioParam {
+	return &audioParam{value: n.value.Get("detune")}
+}
+
+type convolverNode struct {
+	node
+}
+
+func (n *convolverNode) SetBuffer(ir Buffer, normalize bool) error {
+	irBuffer := ir.(*buffer)
+	if irBuffer.value == nil {
+		return fmt.Errorf("invalid impulse response")
+	}
+	// normalize is applied when buffer is set
+	n.value.Set("normalize", normalize)
+	n.value.Set("buffer", *irBuffer.value)
+	return nil
+}
+
+type analyserNode struct {
+	node
+	// JS arrays reused between calls
+	floatData js.Value
+	byteData  js.Value
+}
+
+func (n *analyserNode) FFTSize(size int) error {
+	if err := checkFFTSize(size); err != nil {
+		return err
+	}
+	n.value.Set("fftSize", size)
+	return nil
+}
+
+func (n *analyserNode) FrequencyBinCount() int {
+	return n.value.Get("frequencyBinCount").Int()
+}
+
+func (n *analyserNode) SmoothingTimeConstant(value float32) {
+	n.value.Set("smoothingTimeConstant", math.Max(0, math.Min(float64(value), 1)))
+}
+
+func (n *analyserNode) GetFloatFrequencyData(data []float32) {
+	n.floatData = reuseArray(n.floatData, "Float32Array", len(data))
+	n.value.Call("getFloatFrequencyData", n.floatData)
+	copy(data, jsToFloat32Slice(n.floatData))
+}
+
+func (n *analyserNode) GetByteFrequencyData(data []byte) {
+	n.byteData = reuseArray(n.byteData, "Uint8Array", len(data))
+	n.value.Call("getByteFrequencyData", n.byteData)
+	js.CopyBytesToGo(data, n.byteData)
+}
+
+func (n *analyserNode) GetFloatTimeDomainData(data []float32) {
+	n.floatData = reuseArray(n.floatData, "Float32Array", len(data))
+	n.value.Call("getFloatTimeDomainData", n.floatData)
+	copy(data, jsToFloat32Slice(n.floatData))
+}
+
+type waveShaperNode struct {
+	node
+}
+
+func (n *waveShaperNode) SetCurve(curve []float32) error {
+	if curve == nil {
+		n.value.Set("curve", js.Null())
+		return nil
+	}
+	if len(curve) < 2 {
+		return fmt.Errorf("invalid curve length %d", len(curve))
+	}
+	n.value.Set("curve", float32SliceToJS(curve))
+	return nil
+}
+
+func (n *waveShaperNode) Oversample(oversample OverSampleType) {
+	switch oversample {
+	case OverSample2x, OverSample4x:
+		n.value.Set("oversample", oversample.String())
+	default:
+		n.value.Set("oversample", OverSampleNone.String())
+	}
+}
+
+type delayNode struct {
+	node
+}
+
+func (n *delayNode) DelayTime(value float32) {
+	n.value.Get("delayTime").Set("value", value)
+}
+
+func (n *delayNode) DelayTimeParam() AudioParam {
+	return &audioParam{value: n.value.Get("delayTime")}
+}
+
+type dynamicsCompressorNode struct {
+	node
+}
+
+func (n *dynamicsCompressorNode) Threshold(value float32) {
+	n.value.Get("threshold").Set("value", value)
+}
+
+func (n *dynamicsCompressorNode) ThresholdParam() AudioParam {
+	return &audioParam{value: n.value.Get("threshold")}
+}
+
+func (n *dynamicsCompressorNode) Knee(value float32) {
+	n.value.Get("knee").Set("value", value)
+}
+
+func (n *dynamicsCompressorNode) KneeParam() AudioParam {
+	return &audioParam{value: n.value.Get("knee")}
+}
+
+func (n *dynamicsCompressorNode) Ratio(value float32) {
+	n.value.Get("ratio").Set("value", value)
+}
+
+func (n *dynamicsCompressorNode) RatioParam() AudioParam {
+	return &audioParam{value: n.value.Get("ratio")}
+}
+
+func (n *dynamicsCompressorNode) Attack(value float32) {
+	n.value.Get("attack").Set("value", value)
+}
+
+func (n *dynamicsCompressorNode) AttackParam() AudioParam {
+	return &audioParam{value: n.value.Get("attack")}
+}
+
+func (n *dynamicsCompressorNode) Release(value float32) {
+	n.value.Get("release").Set("value", value)
+}
+
+func (n *dynamicsCompressorNode) ReleaseParam() AudioParam {
+	return &audioParam{value: n.value.Get("release")}
+}
+
+func (n *dynamicsCompressorNode) Reduction() float32 {
+	reduction := n.value.Get("reduction")
+	// Older implementations expose reduction as an AudioParam
+	if reduction.Type() == js.TypeObject {
+		return float32(reduction.Get("value").Float())
+	}
+	return float32(reduction.Float())
+}
+
+func currentTime() float64 {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return 0
+		}
+	}
+	return _pluginInstance.audioCtx.Get("currentTime").Float()
+}
+
 func createContext() error {
 	audioContextClass := js.Global().Get("AudioContext")
 
@@ -163,10 +750,104 @@ func createContext() error {
 
 	_pluginInstance.audioCtx = &audioCtx
 	_pluginInstance.isInit = true
+	masterInput := audioCtx.Call("createGain")
+	masterInput.Call("connect", audioCtx.Get("destination"))
+	_pluginInstance.masterInput = &masterInput
+	_pluginInstance.closed = false
+	// Autoplay may be allowed by browser
+	_pluginInstance.unlocked = audioCtx.Get("state").String() == "running"
+
+	// Page visibility is handled as application lifecycle
+	document := js.Global().Get("document")
+	_pluginInstance.visibilityFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if document.Get("hidden").Bool() {
+			lifecycleSuspend()
+		} else {
+			lifecycleResume()
+		}
+		return nil
+	})
+	document.Call("addEventListener", "visibilitychange", _pluginInstance.visibilityFunc)
+
+	return nil
+}
+
+func setMasterLimiter(enabled bool) error {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return err
+		}
+	}
+	if enabled == (_pluginInstance.masterLimiter != nil) {
+		return nil
+	}
+	destination := _pluginInstance.audioCtx.Get("destination")
+	_pluginInstance.masterInput.Call("disconnect")
+	if enabled {
+		limiter := _pluginInstance.audioCtx.Call("createDynamicsCompressor")
+		if limiter == js.Undefined() || limiter == js.Null() {
+			_pluginInstance.masterInput.Call("connect", destination)
+			return fmt.Errorf("failed to create JS DynamicsCompressorNode")
+		}
+		limiter.Get("threshold").Set("value", -1)
+		limiter.Get("knee").Set("value", 0)
+		limiter.Get("ratio").Set("value", 20)
+		limiter.Get("attack").Set("value", 0)
+		limiter.Get("release").Set("value", 0.1)
+		_pluginInstance.masterInput.Call("connect", limiter)
+		limiter.Call("connect", destination)
+		_pluginInstance.masterLimiter = &limiter
+	} else {
+		_pluginInstance.masterLimiter.Call("disconnect")
+		_pluginInstance.masterInput.Call("connect", destination)
+		_pluginInstance.masterLimiter = nil
+	}
+	return nil
+}
 
+func suspend() error {
+	if !_pluginInstance.isInit {
+		if _pluginInstance.closed {
+			return fmt.Errorf("audio context is closed")
+		}
+		if err := createContext(); err != nil {
+			return err
+		}
+	}
+	_pluginInstance.audioCtx.Call("suspend")
 	return nil
 }
 
+func resume() error {
+	if !_pluginInstance.isInit {
+		if _pluginInstance.closed {
+			return fmt.Errorf("audio context is closed")
+		}
+		if err := createContext(); err != nil {
+			return err
+		}
+	}
+	_pluginInstance.audioCtx.Call("resume")
+	return nil
+}
+
+func state() ContextState {
+	if !_pluginInstance.isInit {
+		if _pluginInstance.closed {
+			return ContextClosed
+		}
+		// Context is created suspended until user interaction
+		return ContextSuspended
+	}
+	switch _pluginInstance.audioCtx.Get("state").String() {
+	case "running":
+		return ContextRunning
+	case "closed":
+		return ContextClosed
+	}
+	return ContextSuspended
+}
+
 func createBuffer(path string) (Buffer, error) {
 	if !_pluginInstance.isInit {
 		if err := createContext(); err != nil {
@@ -200,6 +881,68 @@ func createBuffer(path string) (Buffer, error) {
 	return &buffer, nil
 }
 
+func createBufferFromBytes(data []byte, format string) (Buffer, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	doneState := make(chan bool)
+	buffer := buffer{}
+
+	jsData := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(jsData, data)
+
+	onDecodeSuccessCallback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		buffer.value = &args[0]
+		doneState <- true
+		return false
+	})
+	defer onDecodeSuccessCallback.Release()
+
+	onDecodeErrorCallback := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) > 0 && args[0] != js.Undefined() && args[0] != js.Null() {
+			err = fmt.Errorf("failed to decode audio data: %s", args[0].Get("message").String())
+		} else {
+			err = fmt.Errorf("failed to decode audio data")
+		}
+		doneState <- false
+		return false
+	})
+	defer onDecodeErrorCallback.Release()
+
+	_pluginInstance.audioCtx.Call("decodeAudioData", jsData.Get("buffer"), onDecodeSuccessCallback, onDecodeErrorCallback)
+
+	<-doneState
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &buffer, nil
+}
+
+func createBufferFromPCM(channels [][]float32, sampleRate int) (Buffer, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsAudioBuffer := _pluginInstance.audioCtx.Call("createBuffer", len(channels), len(channels[0]), sampleRate)
+
+	if jsAudioBuffer == js.Undefined() || jsAudioBuffer == js.Null() {
+		return nil, fmt.Errorf("failed to create JS AudioBuffer")
+	}
+
+	for c, channel := range channels {
+		jsAudioBuffer.Call("copyToChannel", float32SliceToJS(channel), c)
+	}
+
+	return &buffer{value: &jsAudioBuffer}, nil
+}
+
 func createBufferSourceNode(buf Buffer) (BufferSourceNode, error) {
 	if !_pluginInstance.isInit {
 		if err := createContext(); err != nil {
@@ -217,6 +960,13 @@ func createBufferSourceNode(buf Buffer) (BufferSourceNode, error) {
 
 	node := &bufferSourceNode{}
 	node.value = &jsBufferSourceNode
+	node.endedFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		// Sources can only be played once
+		node.endedFunc.Release()
+		go fireEnded(node, node.onEnded)
+		return nil
+	})
+	jsBufferSourceNode.Set("onended", node.endedFunc)
 
 	return node, nil
 }
@@ -239,6 +989,53 @@ func createMediaElementSourceNode(path string) (MediaElementSourceNode, error) {
 	jsHtmlElement := jsMediaElementObjet.Get("htmlElement")
 	node.value = &jsMediaElementSourceNode
 	node.htmlElement = &jsHtmlElement
+	node.endedFunc = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go fireEnded(node, node.onEnded)
+		return nil
+	})
+	jsHtmlElement.Call("addEventListener", "ended", node.endedFunc)
+
+	return node, nil
+}
+
+func createOscillatorNode() (OscillatorNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsOscillatorNode := _pluginInstance.audioCtx.Call("createOscillator")
+
+	if jsOscillatorNode == js.Undefined() || jsOscillatorNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS OscillatorNode")
+	}
+
+	node := &oscillatorNode{}
+	node.value = &jsOscillatorNode
+	node.source = node
+	node.listenEnded()
+
+	return node, nil
+}
+
+func createConstantSourceNode() (ConstantSourceNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsConstantSourceNode := _pluginInstance.audioCtx.Call("createConstantSource")
+
+	if jsConstantSourceNode == js.Undefined() || jsConstantSourceNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS ConstantSourceNode")
+	}
+
+	node := &constantSourceNode{}
+	node.value = &jsConstantSourceNode
+	node.source = node
+	node.listenEnded()
 
 	return node, nil
 }
@@ -250,7 +1047,8 @@ func createDestinationNode() (DestinationNode, error) {
 		}
 	}
 
-	jsDestinationNode := _pluginInstance.audioCtx.Get("destination")
+	// Nodes are connected to master input in front of the actual destination
+	jsDestinationNode := *_pluginInstance.masterInput
 
 	if jsDestinationNode == js.Undefined() || jsDestinationNode == js.Null() {
 		return nil, fmt.Errorf("failed to create JS AudioDestinationNode")
@@ -299,3 +1097,156 @@ func createGainNode() (GainNode, error) {
 
 	return node, nil
 }
+
+func createBiquadFilterNode() (BiquadFilterNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsBiquadFilterNode := _pluginInstance.audioCtx.Call("createBiquadFilter")
+
+	if jsBiquadFilterNode == js.Undefined() || jsBiquadFilterNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS BiquadFilterNode")
+	}
+
+	node := &biquadFilterNode{}
+	node.value = &jsBiquadFilterNode
+
+	return node, nil
+}
+
+func createConvolverNode(ir Buffer, normalize bool) (ConvolverNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsConvolverNode := _pluginInstance.audioCtx.Call("createConvolver")
+
+	if jsConvolverNode == js.Undefined() || jsConvolverNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS ConvolverNode")
+	}
+
+	node := &convolverNode{}
+	node.value = &jsConvolverNode
+	if err := node.SetBuffer(ir, normalize); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func createDynamicsCompressorNode() (DynamicsCompressorNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsDynamicsCompressorNode := _pluginInstance.audioCtx.Call("createDynamicsCompressor")
+
+	if jsDynamicsCompressorNode == js.Undefined() || jsDynamicsCompressorNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS DynamicsCompressorNode")
+	}
+
+	node := &dynamicsCompressorNode{}
+	node.value = &jsDynamicsCompressorNode
+
+	return node, nil
+}
+
+func createAnalyserNode() (AnalyserNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsAnalyserNode := _pluginInstance.audioCtx.Call("createAnalyser")
+
+	if jsAnalyserNode == js.Undefined() || jsAnalyserNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS AnalyserNode")
+	}
+
+	node := &analyserNode{}
+	node.value = &jsAnalyserNode
+
+	return node, nil
+}
+
+func createWaveShaperNode() (WaveShaperNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsWaveShaperNode := _pluginInstance.audioCtx.Call("createWaveShaper")
+
+	if jsWaveShaperNode == js.Undefined() || jsWaveShaperNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS WaveShaperNode")
+	}
+
+	node := &waveShaperNode{}
+	node.value = &jsWaveShaperNode
+
+	return node, nil
+}
+
+func createDelayNode(maxDelay float32) (DelayNode, error) {
+	if !_pluginInstance.isInit {
+		if err := createContext(); err != nil {
+			return nil, err
+		}
+	}
+
+	jsDelayNode := _pluginInstance.audioCtx.Call("createDelay", maxDelay)
+
+	if jsDelayNode == js.Undefined() || jsDelayNode == js.Null() {
+		return nil, fmt.Errorf("failed to create JS DelayNode")
+	}
+
+	node := &delayNode{}
+	node.value = &jsDelayNode
+
+	return node, nil
+}
+
+// -------------------------------------------------------------------- //
+// Tooling
+// -------------------------------------------------------------------- //
+
+// float32SliceToJS copies a float32 slice into a new JS Float32Array
+func float32SliceToJS(values []float32) js.Value {
+	bytes := make([]byte, 4*len(values))
+	for i, v := range values {
+		// WebAssembly is little endian
+		binary.LittleEndian.PutUint32(bytes[4*i:], math.Float32bits(v))
+	}
+	jsBytes := js.Global().Get("Uint8Array").New(len(bytes))
+	js.CopyBytesToJS(jsBytes, bytes)
+	return js.Global().Get("Float32Array").New(jsBytes.Get("buffer"))
+}
+
+// reuseArray returns array if it is a JS typed array of given type and length, a new one otherwise
+func reuseArray(array js.Value, arrayType string, length int) js.Value {
+	if array.Type() == js.TypeObject && array.Get("length").Int() == length {
+		return array
+	}
+	return js.Global().Get(arrayType).New(length)
+}
+
+// jsToFloat32Slice copies a JS Float32Array into a new float32 slice
+func jsToFloat32Slice(value js.Value) []float32 {
+	jsBytes := js.Global().Get("Uint8Array").New(value.Get("buffer"), value.Get("byteOffset"), value.Get("byteLength"))
+	bytes := make([]byte, jsBytes.Get("length").Int())
+	js.CopyBytesToGo(bytes, jsBytes)
+	values := make([]float32, len(bytes)/4)
+	for i := range values {
+		values[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[4*i:]))
+	}
+	return values
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+)
+
+// -------------------------------------------------------------------- //
+// BiquadFilterNode implementation
+// -------------------------------------------------------------------- //
+
+// biquadFilterNode is rendered in software, coefficients follow the WebAudio specification
+// (Audio EQ Cookbook) and are computed once per block
+type biquadFilterNode struct {
+	processorNode
+	filterType BiquadFilterType
+	frequency  *audioParam
+	q          *audioParam
+	gain       *audioParam
+	detune     *audioParam
+	// Normalized coefficients
+	b0, b1, b2, a1, a2 float64
+	// State per channel
+	x1, x2, y1, y2 [2]float64
+}
+
+func (n *biquadFilterNode) Type(filterType BiquadFilterType) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.filterType = filterType
+}
+
+func (n *biquadFilterNode) Frequency(value float32) {
+	n.frequency.SetValue(value)
+}
+
+func (n *biquadFilterNode) FrequencyParam() AudioParam {
+	return n.frequency
+}
+
+func (n *biquadFilterNode) Q(value float32) {
+	n.q.SetValue(value)
+}
+
+func (n *biquadFilterNode) QParam() AudioParam {
+	return n.q
+}
+
+func (n *biquadFilterNode) Gain(value float32) {
+	n.gain.SetValue(value)
+}
+
+func (n *biquadFilterNode) GainParam() AudioParam {
+	return n.gain
+}
+
+func (n *biquadFilterNode) Detune(cents float32) {
+	n.detune.SetValue(cents)
+}
+
+func (n *biquadFilterNode) DetuneParam() AudioParam {
+	return n.detune
+}
+
+// render filters inputs
+func (n *biquadFilterNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(out, false)
+	n.computeCoefficients()
+	for c := range out {
+		x1, x2, y1, y2 := n.x1[c], n.x2[c], n.y1[c], n.y2[c]
+		for i, v := range out[c] {
+			x := float64(v)
+			y := n.b0*x + n.b1*x1 + n.b2*x2 - n.a1*y1 - n.a2*y2
+			x2, x1 = x1, x
+			y2, y1 = y1, y
+			out[c][i] = float32(y)
+		}
+		// Flush denormals and recover from unstable settings
+		if math.Abs(y1) < 1e-30 || math.IsNaN(y1) || math.IsInf(y1, 0) {
+			y1, y2 = 0, 0
+		}
+		n.x1[c], n.x2[c], n.y1[c], n.y2[c] = x1, x2, y1, y2
+	}
+}
+
+// computeCoefficients computes filter coefficients from params at current render time
+func (n *biquadFilterNode) computeCoefficients() {
+	nyquist := float64(renderSampleRate) / 2
+	frequency := float64(n.frequency.renderValue(renderTime)) * math.Pow(2, float64(n.detune.renderValue(renderTime))/1200)
+	frequency = math.Max(0, math.Min(frequency, nyquist)) / nyquist
+	q := float64(n.q.renderValue(renderTime))
+	gain := float64(n.gain.renderValue(renderTime))
+
+	A := math.Pow(10, gain/40)
+	w0 := math.Pi * frequency
+	cosW0, sinW0 := math.Cos(w0), math.Sin(w0)
+	alphaQ := sinW0 / (2 * q)
+	alphaQdB := sinW0 / (2 * math.Pow(10, q/20))
+	alphaS := sinW0 / 2 * math.Sqrt(2)
+	sqrtA := math.Sqrt(A)
+
+	var b0, b1, b2, a0, a1, a2 float64
+	switch n.filterType {
+	case BiquadLowpass:
+		b0, b1, b2 = (1-cosW0)/2, 1-cosW0, (1-cosW0)/2
+		a0, a1, a2 = 1+alphaQdB, -2*cosW0, 1-alphaQdB
+	case BiquadHighpass:
+		b0, b1, b2 = (1+cosW0)/2, -(1 + cosW0), (1+cosW0)/2
+		a0, a1, a2 = 1+alphaQdB, -2*cosW0, 1-alphaQdB
+	case BiquadBandpass:
+		b0, b1, b2 = alphaQ, 0, -alphaQ
+		a0, a1, a2 = 1+alphaQ, -2*cosW0, 1-alphaQ
+	case BiquadLowshelf:
+		b0 = A * ((A + 1) - (A-1)*cosW0 + 2*alphaS*sqrtA)
+		b1 = 2 * A * ((A - 1) - (A+1)*cosW0)
+		b2 = A * ((A + 1) - (A-1)*cosW0 - 2*alphaS*sqrtA)
+		a0 = (A + 1) + (A-1)*cosW0 + 2*alphaS*sqrtA
+		a1 = -2 * ((A - 1) + (A+1)*cosW0)
+		a2 = (A + 1) + (A-1)*cosW0 - 2*alphaS*sqrtA
+	case BiquadHighshelf:
+		b0 = A * ((A + 1) + (A-1)*cosW0 + 2*alphaS*sqrtA)
+		b1 = -2 * A * ((A - 1) + (A+1)*cosW0)
+		b2 = A * ((A + 1) + (A-1)*cosW0 - 2*alphaS*sqrtA)
+		a0 = (A + 1) - (A-1)*cosW0 + 2*alphaS*sqrtA
+		a1 = 2 * ((A - 1) - (A+1)*cosW0)
+		a2 = (A + 1) - (A-1)*cosW0 - 2*alphaS*sqrtA
+	case BiquadPeaking:
+		b0, b1, b2 = 1+alphaQ*A, -2*cosW0, 1-alphaQ*A
+		a0, a1, a2 = 1+alphaQ/A, -2*cosW0, 1-alphaQ/A
+	case BiquadNotch:
+		b0, b1, b2 = 1, -2*cosW0, 1
+		a0, a1, a2 = 1+alphaQ, -2*cosW0, 1-alphaQ
+	case BiquadAllpass:
+		b0, b1, b2 = 1-alphaQ, -2*cosW0, 1+alphaQ
+		a0, a1, a2 = 1+alphaQ, -2*cosW0, 1-alphaQ
+	default:
+		b0, a0 = 1, 1
+	}
+
+	// Degenerated settings (Q or frequency at 0), let signal pass
+	if a0 == 0 || math.IsNaN(a0) || math.IsInf(a0, 0) || math.IsNaN(b0+b1+b2+a1+a2) {
+		b0, b1, b2, a0, a1, a2 = 1, 0, 0, 1, 0, 0
+	}
+	n.b0, n.b1, n.b2, n.a1, n.a2 = b0/a0, b1/a0, b2/a0, a1/a0, a2/a0
+}
+
+func createBiquadFilterNode() (BiquadFilterNode, error) {
+	filter := &biquadFilterNode{
+		processorNode: newProcessorNode(),
+	}
+	nyquist := float32(renderSampleRate) / 2
+	filter.frequency = newAudioParam(350, 0, nyquist, nil)
+	filter.q = newAudioParam(1, -math.MaxFloat32, math.MaxFloat32, nil)
+	filter.gain = newAudioParam(0, -math.MaxFloat32, 1541, nil)
+	filter.detune = newAudioParam(0, -153600, 153600, nil)
+	filter.process = filter.render
+	return filter, nil
+}
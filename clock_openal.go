@@ -0,0 +1,187 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	sort "sort"
+	sync "sync"
+	time "time"
+
+	al "github.com/thommil/tge-mobile/exp/audio/al"
+)
+
+// -------------------------------------------------------------------- //
+// Clock & Scheduler
+// -------------------------------------------------------------------- //
+
+// Starts scheduled within this window are played together in a single AL call
+const schedulerWindow = 0.002
+
+var clockMutex sync.Mutex
+
+// clockStart is the origin of the audio context clock, time.Time uses the monotonic clock
+var clockStart = time.Now()
+
+// clockPausedAt is the time at which clock has been paused, zero if running
+var clockPausedAt time.Time
+
+func currentTime() float64 {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	if !clockPausedAt.IsZero() {
+		return clockPausedAt.Sub(clockStart).Seconds()
+	}
+	return time.Since(clockStart).Seconds()
+}
+
+// resetClock restarts clock from 0
+func resetClock() {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	clockStart = time.Now()
+	clockPausedAt = time.Time{}
+}
+
+// pauseClock stops the clock until resumeClock is called
+func pauseClock() {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	if clockPausedAt.IsZero() {
+		clockPausedAt = time.Now()
+	}
+}
+
+// resumeClock restarts the clock where it has been paused and wakes up scheduler
+func resumeClock() {
+	clockMutex.Lock()
+	if !clockPausedAt.IsZero() {
+		clockStart = clockStart.Add(time.Since(clockPausedAt))
+		clockPausedAt = time.Time{}
+	}
+	clockMutex.Unlock()
+	wakeUpScheduler()
+}
+
+func clockPaused() bool {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	return !clockPausedAt.IsZero()
+}
+
+// sleepUntilOrWake blocks until the given time of the audio context clock or until notified on wake,
+// returns false if woken. Time spent suspended is not counted.
+func sleepUntilOrWake(when float64, wake chan bool) bool {
+	for delay := when - currentTime(); delay > 0; delay = when - currentTime() {
+		timer := time.NewTimer(time.Duration(delay * float64(time.Second)))
+		select {
+		case <-wake:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+	return true
+}
+
+// scheduledStart holds the settings of a buffer source start
+type scheduledStart struct {
+	node       *bufferSourceNode
+	generation uint32
+	when       float64
+	offset     float32
+	duration   float32
+	loop       bool
+	loopStart  float32
+	loopEnd    float32
+	// bufferDuration is set once prepared
+	bufferDuration float32
+}
+
+var schedulerMutex sync.Mutex
+var scheduledStarts = make([]*scheduledStart, 0, sourcePoolSize)
+var schedulerWakeUp = make(chan bool, 1)
+var schedulerOnce sync.Once
+
+// scheduleStart adds a start to scheduler queue sorted by time
+func scheduleStart(start *scheduledStart) {
+	schedulerOnce.Do(func() {
+		go schedulerLoop()
+	})
+
+	schedulerMutex.Lock()
+	i := sort.Search(len(scheduledStarts), func(i int) bool {
+		return scheduledStarts[i].when > start.when
+	})
+	scheduledStarts = append(scheduledStarts, nil)
+	copy(scheduledStarts[i+1:], scheduledStarts[i:])
+	scheduledStarts[i] = start
+	schedulerMutex.Unlock()
+
+	wakeUpScheduler()
+}
+
+// wakeUpScheduler notifies scheduler loop of changes in queue or clock
+func wakeUpScheduler() {
+	select {
+	case schedulerWakeUp <- true:
+	default:
+	}
+}
+
+// cancelStart removes pending starts of the given node
+func cancelStart(n *bufferSourceNode) {
+	schedulerMutex.Lock()
+	defer schedulerMutex.Unlock()
+	for i := 0; i < len(scheduledStarts); i++ {
+		if scheduledStarts[i].node == n {
+			scheduledStarts = append(scheduledStarts[:i], scheduledStarts[i+1:]...)
+			i--
+		}
+	}
+}
+
+// schedulerLoop plays scheduled starts on time, starts due in the same window are played at once,
+// nothing is played while clock is paused
+func schedulerLoop() {
+	due := make([]*scheduledStart, 0, sourcePoolSize)
+	handles := make([]al.Source, 0, sourcePoolSize)
+	for {
+		schedulerMutex.Lock()
+		if len(scheduledStarts) == 0 || clockPaused() {
+			schedulerMutex.Unlock()
+			<-schedulerWakeUp
+			continue
+		}
+		now := currentTime()
+		if delay := scheduledStarts[0].when - now; delay > schedulerWindow/2 {
+			schedulerMutex.Unlock()
+			select {
+			case <-time.After(time.Duration((delay - schedulerWindow/2) * float64(time.Second))):
+			case <-schedulerWakeUp:
+			}
+			continue
+		}
+		due, handles = due[:0], handles[:0]
+		for len(scheduledStarts) > 0 && scheduledStarts[0].when <= now+schedulerWindow {
+			due = append(due, scheduledStarts[0])
+			scheduledStarts = scheduledStarts[1:]
+		}
+		schedulerMutex.Unlock()
+
+		prepared := due[:0]
+		for _, start := range due {
+			if start.node.prepare(start) {
+				prepared = append(prepared, start)
+				handles = append(handles, start.node.sources[0].handle)
+			}
+		}
+		if len(handles) > 0 {
+			al.PlaySources(handles...)
+		}
+		for _, start := range prepared {
+			start.node.started(start)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+	atomic "sync/atomic"
+)
+
+// -------------------------------------------------------------------- //
+// DynamicsCompressorNode implementation
+// -------------------------------------------------------------------- //
+
+const (
+	// Delay of signal in seconds so that gain reduction is applied before peaks
+	compressorLookahead = 0.006
+	// Settings of master limiter
+	limiterThreshold = -1
+	limiterRelease   = 0.1
+	limiterLookahead = 0.005
+)
+
+// compressor applies a soft knee compression curve, gain reduction is smoothed by attack and release
+// and applied on delayed signal (lookahead)
+type compressor struct {
+	line     [2][]float32
+	position int
+	// reduction is the current gain reduction in dB
+	reduction float64
+}
+
+func newCompressor(lookahead float64) *compressor {
+	c := &compressor{}
+	for i := range c.line {
+		c.line[i] = make([]float32, int(lookahead*renderSampleRate)+1)
+	}
+	return c
+}
+
+// process compresses out in place, makeup applies the makeup gain defined by WebAudio specification
+func (c *compressor) process(out *renderBlock, threshold, knee, ratio, attack, release float64, makeup bool) {
+	attackCoef := compressorCoef(attack)
+	releaseCoef := compressorCoef(release)
+	makeupGain := 1.0
+	if makeup {
+		// Gain to have full range output for a full range input
+		makeupGain = math.Pow(1/dbToLinear(compressorCurve(0, threshold, knee, ratio)), 0.6)
+	}
+	length := len(c.line[0])
+	for i := 0; i < renderBlockSize; i++ {
+		peak := math.Max(math.Abs(float64(out[0][i])), math.Abs(float64(out[1][i])))
+		level := linearToDb(peak)
+		target := compressorCurve(level, threshold, knee, ratio) - level
+		if target < c.reduction {
+			c.reduction = target + (c.reduction-target)*attackCoef
+		} else {
+			c.reduction = target + (c.reduction-target)*releaseCoef
+		}
+		gain := float32(dbToLinear(c.reduction) * makeupGain)
+		for ch := range out {
+			delayed := c.line[ch][c.position]
+			c.line[ch][c.position] = out[ch][i]
+			out[ch][i] = delayed * gain
+		}
+		c.position = (c.position + 1) % length
+	}
+}
+
+// compressorCurve returns the output level in dB of an input level in dB
+func compressorCurve(level, threshold, knee, ratio float64) float64 {
+	switch {
+	case level < threshold-knee/2:
+		return level
+	case level > threshold+knee/2 || knee <= 0:
+		return threshold + (level-threshold)/ratio
+	}
+	over := level - threshold + knee/2
+	return level + (1/ratio-1)*over*over/(2*knee)
+}
+
+// compressorCoef returns the smoothing coefficient of a time constant in seconds
+func compressorCoef(time float64) float64 {
+	if time <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (time * renderSampleRate))
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func linearToDb(value float64) float64 {
+	if value < 1e-6 {
+		return -120
+	}
+	return 20 * math.Log10(value)
+}
+
+// dynamicsCompressorNode is rendered in software
+type dynamicsCompressorNode struct {
+	processorNode
+	threshold  *audioParam
+	knee       *audioParam
+	ratio      *audioParam
+	attack     *audioParam
+	release    *audioParam
+	compressor *compressor
+}
+
+func (n *dynamicsCompressorNode) Threshold(value float32) {
+	n.threshold.SetValue(value)
+}
+
+func (n *dynamicsCompressorNode) ThresholdParam() AudioParam {
+	return n.threshold
+}
+
+func (n *dynamicsCompressorNode) Knee(value float32) {
+	n.knee.SetValue(value)
+}
+
+func (n *dynamicsCompressorNode) KneeParam() AudioParam {
+	return n.knee
+}
+
+func (n *dynamicsCompressorNode) Ratio(value float32) {
+	n.ratio.SetValue(value)
+}
+
+func (n *dynamicsCompressorNode) RatioParam() AudioParam {
+	return n.ratio
+}
+
+func (n *dynamicsCompressorNode) Attack(value float32) {
+	n.attack.SetValue(value)
+}
+
+func (n *dynamicsCompressorNode) AttackParam() AudioParam {
+	return n.attack
+}
+
+func (n *dynamicsCompressorNode) Release(value float32) {
+	n.release.SetValue(value)
+}
+
+func (n *dynamicsCompressorNode) ReleaseParam() AudioParam {
+	return n.release
+}
+
+func (n *dynamicsCompressorNode) Reduction() float32 {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	return float32(n.compressor.reduction)
+}
+
+// render compresses inputs
+func (n *dynamicsCompressorNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(out, false)
+	n.compressor.process(out,
+		float64(n.threshold.renderValue(renderTime)),
+		float64(n.knee.renderValue(renderTime)),
+		float64(n.ratio.renderValue(renderTime)),
+		float64(n.attack.renderValue(renderTime)),
+		float64(n.release.renderValue(renderTime)),
+		true)
+}
+
+func createDynamicsCompressorNode() (DynamicsCompressorNode, error) {
+	compressor := &dynamicsCompressorNode{
+		processorNode: newProcessorNode(),
+		compressor:    newCompressor(compressorLookahead),
+	}
+	compressor.threshold = newAudioParam(-24, -100, 0, nil)
+	compressor.knee = newAudioParam(30, 0, 40, nil)
+	compressor.ratio = newAudioParam(12, 1, 20, nil)
+	compressor.attack = newAudioParam(0.003, 0, 1, nil)
+	compressor.release = newAudioParam(0.25, 0, 1, nil)
+	compressor.process = compressor.render
+	return compressor, nil
+}
+
+// Master limiter
+
+// masterLimiter is applied on the output of software rendering, nil if disabled
+var masterLimiter *compressor
+
+// masterLimited is set to 1 while master limiter is enabled, AL sources are then muted and rendered
+// in software
+var masterLimited int32
+
+func setMasterLimiter(enabled bool) error {
+	if enabled {
+		startRenderEngine()
+	}
+	sources := make([]*sourceProxy, 0, len(bufferSourceNodes))
+	for _, n := range bufferSourceNodes {
+		sources = append(sources, n.sources[0])
+	}
+	contextMutex.Lock()
+	for n := range mediaElementNodes {
+		sources = append(sources, n.sources[0])
+	}
+	contextMutex.Unlock()
+
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if enabled == (masterLimiter != nil) {
+		return nil
+	}
+	if enabled {
+		masterLimiter = newCompressor(limiterLookahead)
+		atomic.StoreInt32(&masterLimited, 1)
+	} else {
+		masterLimiter = nil
+		atomic.StoreInt32(&masterLimited, 0)
+	}
+
+	// Mute or restore AL sources
+	for _, source := range sources {
+		if source.connected {
+			source.handle.SetGain(source.audibleGain())
+		}
+	}
+	return nil
+}
+
+// limit applies master limiter on out, remaining peaks are clipped
+func limit(out *renderBlock) {
+	masterLimiter.process(out, limiterThreshold, 0, math.Inf(1), 0, limiterRelease, false)
+	for c := range out {
+		for i, v := range out[c] {
+			if v > 1 {
+				out[c][i] = 1
+			} else if v < -1 {
+				out[c][i] = -1
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+)
+
+// -------------------------------------------------------------------- //
+// ConstantSourceNode implementation
+// -------------------------------------------------------------------- //
+
+// constantSourceNode is a source rendered in software
+type constantSourceNode struct {
+	softwareSourceNode
+	offset *audioParam
+}
+
+func (n *constantSourceNode) Offset(value float32) {
+	n.offset.SetValue(value)
+}
+
+func (n *constantSourceNode) OffsetParam() AudioParam {
+	return n.offset
+}
+
+// render outputs offset between start and stop times
+func (n *constantSourceNode) render(out *renderBlock, wet bool) {
+	*out = silentBlock
+	first, last := n.renderRange()
+	if first == last {
+		return
+	}
+	offsets := n.offset.renderValues()
+	for i := first; i < last; i++ {
+		out[0][i], out[1][i] = offsets[i], offsets[i]
+	}
+}
+
+func createConstantSourceNode() (ConstantSourceNode, error) {
+	constant := &constantSourceNode{
+		softwareSourceNode: newSoftwareSourceNode(),
+	}
+	constant.source = constant
+	constant.offset = newAudioParam(1, -math.MaxFloat32, math.MaxFloat32, nil)
+	constant.process = constant.render
+	return constant, nil
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	fmt "fmt"
+	"math"
+)
+
+// -------------------------------------------------------------------- //
+// ConvolverNode implementation
+// -------------------------------------------------------------------- //
+
+// Normalization constants from WebAudio specification
+const (
+	convolverGainCalibration           = 0.00125
+	convolverGainCalibrationSampleRate = 44100
+	convolverMinPower                  = 0.000125
+)
+
+// convolverNode is rendered in software using uniformly partitioned convolution in frequency domain
+// (overlap-save), impulse response is split in partitions of one block. Stereo inputs are packed
+// in complex samples (left + i.right) to be convolved at once by each channel of impulse response.
+type convolverNode struct {
+	processorNode
+	// kernels holds the spectra of impulse response partitions for each channel
+	kernels [][][]complex128
+	// history holds the spectra of past inputs, history[head] is the most recent
+	history [][]complex128
+	head    int
+	// previous is the input of previous block
+	previous renderBlock
+	input    renderBlock
+	sum      []complex128
+}
+
+func (n *convolverNode) SetBuffer(ir Buffer, normalize bool) error {
+	kernels, err := convolverKernels(ir.(*buffer), normalize)
+	if err != nil {
+		return err
+	}
+	history := make([][]complex128, len(kernels[0]))
+	for p := range history {
+		history[p] = make([]complex128, 2*renderBlockSize)
+	}
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.kernels = kernels
+	n.history = history
+	n.head = 0
+	return nil
+}
+
+// render convolves inputs with impulse response
+func (n *convolverNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(&n.input, false)
+	if n.kernels == nil {
+		*out = silentBlock
+		return
+	}
+
+	// Spectrum of previous and current blocks
+	partitions := len(n.history)
+	n.head = (n.head + partitions - 1) % partitions
+	x := n.history[n.head]
+	for i := 0; i < renderBlockSize; i++ {
+		x[i] = complex(float64(n.previous[0][i]), float64(n.previous[1][i]))
+		x[renderBlockSize+i] = complex(float64(n.input[0][i]), float64(n.input[1][i]))
+	}
+	fft(x, false)
+	n.previous = n.input
+
+	scale := 1 / float64(2*renderBlockSize)
+	for k, kernel := range n.kernels {
+		for i := range n.sum {
+			n.sum[i] = 0
+		}
+		for p, h := range kernel {
+			x := n.history[(n.head+p)%partitions]
+			for i, v := range h {
+				n.sum[i] += x[i] * v
+			}
+		}
+		fft(n.sum, true)
+		y := n.sum[renderBlockSize:]
+		switch {
+		case len(n.kernels) == 1:
+			for i, v := range y {
+				out[0][i] = float32(real(v) * scale)
+				out[1][i] = float32(imag(v) * scale)
+			}
+		case k == 0:
+			for i, v := range y {
+				out[0][i] = float32(real(v) * scale)
+			}
+		default:
+			for i, v := range y {
+				out[1][i] = float32(imag(v) * scale)
+			}
+		}
+	}
+}
+
+// convolverKernels resamples impulse response to render rate and computes the spectra of its partitions
+func convolverKernels(ir *buffer, normalize bool) ([][][]complex128, error) {
+	if ir == nil {
+		return nil, fmt.Errorf("invalid impulse response")
+	}
+	// Snapshot, data can be changed or deleted meanwhile
+	graphMutex.Lock()
+	data := make([][]float32, len(ir.data))
+	for c, channel := range ir.data {
+		data[c] = append([]float32(nil), channel...)
+	}
+	graphMutex.Unlock()
+	if len(data) == 0 || len(data[0]) == 0 {
+		return nil, fmt.Errorf("invalid impulse response")
+	}
+	if len(data) > 2 {
+		return nil, fmt.Errorf("unsupported number of channels %d", len(data))
+	}
+
+	// Gain of discrete convolution is proportional to sample rate, resampled response is scaled
+	// to keep the loudness of original one
+	ratio := float64(ir.sampleRate) / renderSampleRate
+	channels := make([][]float64, len(data))
+	for c, channel := range data {
+		channels[c] = convolverResample(channel, ratio)
+	}
+
+	// Normalization scale from WebAudio specification, computed at render rate
+	scale := ratio
+	if normalize {
+		power := 0.0
+		for _, channel := range channels {
+			for _, v := range channel {
+				power += v * v
+			}
+		}
+		power = math.Sqrt(power / float64(len(channels)*len(channels[0])))
+		if power < convolverMinPower || math.IsNaN(power) {
+			power = convolverMinPower
+		}
+		scale = 1 / power * convolverGainCalibration * convolverGainCalibrationSampleRate / renderSampleRate
+	}
+
+	partitions := (len(channels[0]) + renderBlockSize - 1) / renderBlockSize
+	kernels := make([][][]complex128, len(channels))
+	for c, channel := range channels {
+		kernels[c] = make([][]complex128, partitions)
+		for p := range kernels[c] {
+			h := make([]complex128, 2*renderBlockSize)
+			for i, v := range channel[p*renderBlockSize:] {
+				if i == renderBlockSize {
+					break
+				}
+				h[i] = complex(v*scale, 0)
+			}
+			fft(h, false)
+			kernels[c][p] = h
+		}
+	}
+	return kernels, nil
+}
+
+// Number of zero crossings of the resampling sinc on each side, at input rate when upsampling
+const convolverResampleZeroCrossings = 16
+
+// convolverResample resamples channel by ratio (input rate / output rate) using a Blackman windowed sinc,
+// the cutoff is lowered to the output Nyquist frequency when downsampling to avoid aliasing
+func convolverResample(channel []float32, ratio float64) []float64 {
+	if ratio == 1 {
+		samples := make([]float64, len(channel))
+		for i, v := range channel {
+			samples[i] = float64(v)
+		}
+		return samples
+	}
+	cutoff := math.Min(1, 1/ratio)
+	halfWidth := convolverResampleZeroCrossings / cutoff
+	samples := make([]float64, int(math.Ceil(float64(len(channel))/ratio)))
+	for i := range samples {
+		position := float64(i) * ratio
+		first := int(math.Max(0, math.Ceil(position-halfWidth)))
+		last := int(math.Min(float64(len(channel)-1), math.Floor(position+halfWidth)))
+		v := 0.0
+		for k := first; k <= last; k++ {
+			t := position - float64(k)
+			sinc := cutoff
+			if t != 0 {
+				sinc = math.Sin(math.Pi*cutoff*t) / (math.Pi * t)
+			}
+			w := 0.42 + 0.5*math.Cos(math.Pi*t/halfWidth) + 0.08*math.Cos(2*math.Pi*t/halfWidth)
+			v += float64(channel[k]) * sinc * w
+		}
+		samples[i] = v
+	}
+	return samples
+}
+
+func createConvolverNode(ir Buffer, normalize bool) (ConvolverNode, error) {
+	convolver := &convolverNode{
+		processorNode: newProcessorNode(),
+		sum:           make([]complex128, 2*renderBlockSize),
+	}
+	if err := convolver.SetBuffer(ir, normalize); err != nil {
+		return nil, err
+	}
+	convolver.process = convolver.render
+	return convolver, nil
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+package audio
+
+import (
+	fmt "fmt"
+	"io"
+	"sync"
+)
+
+// -------------------------------------------------------------------- //
+// Decoders API
+// -------------------------------------------------------------------- //
+
+// Decoder interface decodes audio data encoded in a given format into PCM samples, decoders are
+// used by Desktop & Mobile backends, browsers rely on their own decoding
+type Decoder interface {
+	// Decode returns a SampleReader on the decoded samples of the given encoded data
+	Decode(data []byte) (SampleReader, error)
+}
+
+// SampleReader interface gives access to decoded interleaved 16 bits PCM samples, it can be
+// backed by fully decoded data or decode incrementally for streaming
+type SampleReader interface {
+	// Channels returns the number of interleaved channels
+	Channels() int
+	// SampleRate returns the number of samples per second for each channel
+	SampleRate() int
+	// Read reads up to len(samples) interleaved samples, returns the number of samples read
+	// and io.EOF once all samples have been read
+	Read(samples []int16) (int, error)
+}
+
+// SampleSeeker interface is implemented by SampleReaders supporting random access
+type SampleSeeker interface {
+	// Length returns the number of samples for each channel of the stream
+	Length() int64
+	// SetPosition sets the position of the next read in samples for each channel
+	SetPosition(position int64) error
+}
+
+// DecoderFunc type is an adapter to use a function decoding data at once as a Decoder, the function
+// returns interleaved 16 bits samples, number of channels and sample rate
+type DecoderFunc func(data []byte) (samples []int16, channels int, sampleRate int, err error)
+
+// Decode implements Decoder interface
+func (f DecoderFunc) Decode(data []byte) (SampleReader, error) {
+	samples, channels, sampleRate, err := f(data)
+	if err != nil {
+		return nil, err
+	}
+	return &pcmReader{
+		samples:    samples,
+		channels:   channels,
+		sampleRate: sampleRate,
+	}, nil
+}
+
+// RegisterDecoder registers a decoder for an audio format identified by its name and signature,
+// the signature is the magic prefix of encoded data. Only bits set in mask are compared, a nil mask
+// compares all bits and a mask shorter than signature compares all bits of remaining bytes.
+//
+// Decoders registered last are checked first, so applications can override built-in ones.
+func RegisterDecoder(name string, signature, mask []byte, decoder Decoder) {
+	decoderFormatsMutex.Lock()
+	defer decoderFormatsMutex.Unlock()
+	decoderFormats = append(decoderFormats, decoderFormat{
+		name:      name,
+		signature: append([]byte(nil), signature...),
+		mask:      append([]byte(nil), mask...),
+		decoder:   decoder,
+	})
+}
+
+// -------------------------------------------------------------------- //
+// Implementation
+// -------------------------------------------------------------------- //
+
+// decoderFormat associates a decoder with its name and signature
+type decoderFormat struct {
+	name      string
+	signature []byte
+	mask      []byte
+	decoder   Decoder
+}
+
+var decoderFormatsMutex sync.Mutex
+var decoderFormats = make([]decoderFormat, 0, 8)
+
+// pcmReader is a SampleReader and SampleSeeker on fully decoded samples
+type pcmReader struct {
+	samples    []int16
+	channels   int
+	sampleRate int
+	// position of next read in samples array
+	position int
+}
+
+func (r *pcmReader) Channels() int {
+	return r.channels
+}
+
+func (r *pcmReader) SampleRate() int {
+	return r.sampleRate
+}
+
+func (r *pcmReader) Read(samples []int16) (int, error) {
+	if r.position >= len(r.samples) {
+		return 0, io.EOF
+	}
+	n := copy(samples, r.samples[r.position:])
+	r.position += n
+	return n, nil
+}
+
+func (r *pcmReader) Length() int64 {
+	return int64(len(r.samples) / r.channels)
+}
+
+func (r *pcmReader) SetPosition(position int64) error {
+	if position < 0 || position > r.Length() {
+		return fmt.Errorf("invalid position %d", position)
+	}
+	r.position = int(position) * r.channels
+	return nil
+}
+
+// readAllSamples reads the remaining samples of a SampleReader
+func readAllSamples(reader SampleReader) ([]int16, error) {
+	// Fully decoded, no copy needed
+	if r, ok := reader.(*pcmReader); ok {
+		samples := r.samples[r.position:]
+		r.position = len(r.samples)
+		return samples, nil
+	}
+	samples := make([]int16, 0, 64*1024)
+	for {
+		if len(samples) == cap(samples) {
+			samples = append(samples, 0)[:len(samples)]
+		}
+		n, err := reader.Read(samples[len(samples):cap(samples)])
+		samples = samples[:len(samples)+n]
+		if err == io.EOF {
+			return samples, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sniffDecoderFormat detects the format of data from registered signatures
+func sniffDecoderFormat(data []byte) (*decoderFormat, error) {
+	decoderFormatsMutex.Lock()
+	defer decoderFormatsMutex.Unlock()
+	for i := len(decoderFormats) - 1; i >= 0; i-- {
+		if matchSignature(decoderFormats[i].signature, decoderFormats[i].mask, data) {
+			f := decoderFormats[i]
+			return &f, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown audio format")
+}
+
+// findDecoderFormat finds a registered format by its name
+func findDecoderFormat(name string) (*decoderFormat, error) {
+	decoderFormatsMutex.Lock()
+	defer decoderFormatsMutex.Unlock()
+	for i := len(decoderFormats) - 1; i >= 0; i-- {
+		if decoderFormats[i].name == name {
+			f := decoderFormats[i]
+			return &f, nil
+		}
+	}
+	return nil, fmt.Errorf("no decoder registered for audio format %s", name)
+}
+
+// matchSignature checks if data starts with the given signature, only bits set in mask are compared
+func matchSignature(signature, mask []byte, data []byte) bool {
+	if len(data) < len(signature) {
+		return false
+	}
+	for i := range signature {
+		m := byte(0xFF)
+		if i < len(mask) {
+			m = mask[i]
+		}
+		if signature[i]&m != data[i]&m {
+			return false
+		}
+	}
+	return true
+}
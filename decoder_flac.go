@@ -0,0 +1,119 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"bytes"
+	fmt "fmt"
+	"io"
+
+	flac "github.com/mewkiz/flac"
+)
+
+func init() {
+	RegisterDecoder("flac", []byte("fLaC"), nil, DecoderFunc(decodeFlac))
+}
+
+// flacDownmix gives for each FLAC channels count the left and right coefficients of each channel
+// following the FLAC channels assignment, LFE is dropped
+// See https://xiph.org/flac/format.html#frame_header
+var flacDownmix = [9][][2]float64{
+	nil,
+	{{1, 1}},
+	{{1, 0}, {0, 1}},
+	// L R C
+	{{1, 0}, {0, 1}, {0.7071, 0.7071}},
+	// FL FR BL BR
+	{{1, 0}, {0, 1}, {0.7071, 0}, {0, 0.7071}},
+	// FL FR FC BL BR
+	{{1, 0}, {0, 1}, {0.7071, 0.7071}, {0.7071, 0}, {0, 0.7071}},
+	// FL FR FC LFE BL BR
+	{{1, 0}, {0, 1}, {0.7071, 0.7071}, {0, 0}, {0.7071, 0}, {0, 0.7071}},
+	// FL FR FC LFE BC SL SR
+	{{1, 0}, {0, 1}, {0.7071, 0.7071}, {0, 0}, {0.5, 0.5}, {0.7071, 0}, {0, 0.7071}},
+	// FL FR FC LFE BL BR SL SR
+	{{1, 0}, {0, 1}, {0.7071, 0.7071}, {0, 0}, {0.7071, 0}, {0, 0.7071}, {0.7071, 0}, {0, 0.7071}},
+}
+
+// decodeFlac decodes a FLAC file (4 to 32 bits, 1 to 8 channels) into interleaved 16 bits samples,
+// multichannel streams are downmixed to stereo, returns samples, number of channels and sample rate
+func decodeFlac(in []byte) ([]int16, int, int, error) {
+	stream, err := flac.New(bytes.NewReader(in))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("flac: %s", err)
+	}
+	defer stream.Close()
+
+	inChannels := int(stream.Info.NChannels)
+	if inChannels < 1 || inChannels > 8 {
+		return nil, 0, 0, fmt.Errorf("flac: unsupported number of channels %d", inChannels)
+	}
+	outChannels := inChannels
+	if inChannels > 2 {
+		outChannels = 2
+	}
+
+	// Downmix coefficients normalized to avoid clipping
+	var downmix [][2]float64
+	if inChannels > 2 {
+		downmix = make([][2]float64, inChannels)
+		var sumLeft, sumRight float64
+		for _, c := range flacDownmix[inChannels] {
+			sumLeft += c[0]
+			sumRight += c[1]
+		}
+		for i, c := range flacDownmix[inChannels] {
+			downmix[i] = [2]float64{c[0] / sumLeft, c[1] / sumRight}
+		}
+	}
+
+	// STREAMINFO gives the exact number of samples per channel (0 if unknown)
+	totalSamples := int(stream.Info.NSamples)
+	data := make([]int16, 0, totalSamples*outChannels)
+
+	bitsPerSample := uint(stream.Info.BitsPerSample)
+	scale := 1 / float64(int64(1)<<(bitsPerSample-1))
+	toInt16 := func(v int32) int16 {
+		if bitsPerSample > 16 {
+			return int16(v >> (bitsPerSample - 16))
+		}
+		return int16(v << (16 - bitsPerSample))
+	}
+
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, 0, 0, fmt.Errorf("flac: %s", err)
+		}
+		if len(frame.Subframes) != inChannels {
+			return nil, 0, 0, fmt.Errorf("flac: frame channels mismatch")
+		}
+		count := len(frame.Subframes[0].Samples)
+		for i := 0; i < count; i++ {
+			if downmix == nil {
+				for _, subframe := range frame.Subframes {
+					data = append(data, toInt16(subframe.Samples[i]))
+				}
+			} else {
+				var left, right float64
+				for c, subframe := range frame.Subframes {
+					v := float64(subframe.Samples[i]) * scale
+					left += v * downmix[c][0]
+					right += v * downmix[c][1]
+				}
+				data = append(data, floatToInt16(left), floatToInt16(right))
+			}
+		}
+	}
+
+	// Trim trailing samples beyond STREAMINFO length
+	if totalSamples > 0 && len(data) > totalSamples*outChannels {
+		data = data[:totalSamples*outChannels]
+	}
+
+	return data, outChannels, int(stream.Info.SampleRate), nil
+}
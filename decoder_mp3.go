@@ -0,0 +1,200 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	fmt "fmt"
+	"io/ioutil"
+
+	mp3 "github.com/hajimehoshi/go-mp3"
+)
+
+func init() {
+	// ID3v2 tagged or raw MPEG-1/2/2.5 Layer III frame sync (with and without CRC)
+	RegisterDecoder("mp3", []byte("ID3"), nil, DecoderFunc(decodeMp3))
+	for _, sync := range []string{"\xFF\xFB", "\xFF\xFA", "\xFF\xF3", "\xFF\xF2", "\xFF\xE3", "\xFF\xE2"} {
+		RegisterDecoder("mp3", []byte(sync), nil, DecoderFunc(decodeMp3))
+	}
+}
+
+// mp3Info holds stream properties read from the first frame header and its Xing/Info or VBRI tag
+type mp3Info struct {
+	channels        int
+	sampleRate      int
+	samplesPerFrame int
+	// frames is the number of audio frames, 0 if no VBR header has been found
+	frames int
+	// hasTagFrame is set when the first frame is a Xing/Info/VBRI frame and contains no audio
+	hasTagFrame bool
+	// encoderDelay and encoderPadding are read from LAME tag, used for gapless playback
+	encoderDelay   int
+	encoderPadding int
+}
+
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},  // MPEG 2.5
+	{0, 0, 0},             // reserved
+	{22050, 24000, 16000}, // MPEG 2
+	{44100, 48000, 32000}, // MPEG 1
+}
+
+// decodeMp3 decodes a MPEG-1/2/2.5 Layer III file into interleaved 16 bits samples,
+// returns samples, number of channels and sample rate
+func decodeMp3(in []byte) ([]int16, int, int, error) {
+	in = mp3SkipTags(in)
+
+	info, err := parseMp3Info(in)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	decoder, err := mp3.NewDecoder(bytes.NewReader(in))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("mp3: %s", err)
+	}
+	pcm, err := ioutil.ReadAll(decoder)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("mp3: %s", err)
+	}
+
+	// Decoder always outputs 16 bits little endian stereo
+	frames := len(pcm) / 4
+	start, end := 0, frames
+	if info.hasTagFrame {
+		start += info.samplesPerFrame
+	}
+	start += info.encoderDelay
+	if info.frames > 0 {
+		if total := start + info.frames*info.samplesPerFrame - info.encoderDelay - info.encoderPadding; total < end {
+			end = total
+		}
+	}
+	if start > end {
+		start = end
+	}
+
+	data := make([]int16, (end-start)*info.channels)
+	for i := start; i < end; i++ {
+		if info.channels == 1 {
+			data[i-start] = int16(binary.LittleEndian.Uint16(pcm[4*i:]))
+		} else {
+			data[2*(i-start)] = int16(binary.LittleEndian.Uint16(pcm[4*i:]))
+			data[2*(i-start)+1] = int16(binary.LittleEndian.Uint16(pcm[4*i+2:]))
+		}
+	}
+
+	return data, info.channels, info.sampleRate, nil
+}
+
+// mp3SkipTags removes leading ID3v2 and trailing ID3v1 tags
+func mp3SkipTags(in []byte) []byte {
+	// ID3v2 : "ID3" + version(2) + flags(1) + syncsafe size(4)
+	for len(in) >= 10 && bytes.Equal(in[0:3], []byte("ID3")) {
+		size := int(in[6]&0x7F)<<21 | int(in[7]&0x7F)<<14 | int(in[8]&0x7F)<<7 | int(in[9]&0x7F)
+		size += 10
+		// Footer present
+		if in[5]&0x10 != 0 {
+			size += 10
+		}
+		if size > len(in) {
+			size = len(in)
+		}
+		in = in[size:]
+	}
+	// ID3v1 : "TAG" + 125 bytes at end of file
+	if len(in) >= 128 && bytes.Equal(in[len(in)-128:len(in)-125], []byte("TAG")) {
+		in = in[:len(in)-128]
+	}
+	return in
+}
+
+// parseMp3Info reads the first valid frame header and the optional VBR header
+func parseMp3Info(in []byte) (*mp3Info, error) {
+	// Find first frame sync
+	offset := -1
+	for i := 0; i+4 <= len(in); i++ {
+		if in[i] == 0xFF && in[i+1]&0xE0 == 0xE0 && mp3ValidHeader(in[i:]) {
+			offset = i
+			break
+		}
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("mp3: no valid frame found")
+	}
+	header := in[offset:]
+
+	version := int(header[1]>>3) & 0x03
+	info := &mp3Info{
+		sampleRate:      mp3SampleRates[version][(header[2]>>2)&0x03],
+		channels:        2,
+		samplesPerFrame: 1152,
+	}
+	mono := header[3]>>6 == 0x03
+	if mono {
+		info.channels = 1
+	}
+	if version != 3 {
+		info.samplesPerFrame = 576
+	}
+
+	// Xing/Info tag is located after side info
+	sideInfoSize := 32
+	switch {
+	case version == 3 && mono:
+		sideInfoSize = 17
+	case version != 3 && mono:
+		sideInfoSize = 9
+	case version != 3:
+		sideInfoSize = 17
+	}
+
+	var xing, vbri []byte
+	if len(header) > 4+sideInfoSize {
+		xing = header[4+sideInfoSize:]
+	}
+	if len(header) > 4+32 {
+		vbri = header[4+32:]
+	}
+	if len(xing) >= 8 && (bytes.Equal(xing[0:4], []byte("Xing")) || bytes.Equal(xing[0:4], []byte("Info"))) {
+		info.hasTagFrame = true
+		flags := binary.BigEndian.Uint32(xing[4:8])
+		pos := 8
+		if flags&0x01 != 0 && len(xing) >= pos+4 {
+			info.frames = int(binary.BigEndian.Uint32(xing[pos:]))
+			pos += 4
+		}
+		if flags&0x02 != 0 {
+			pos += 4 // bytes
+		}
+		if flags&0x04 != 0 {
+			pos += 100 // TOC
+		}
+		if flags&0x08 != 0 {
+			pos += 4 // quality
+		}
+		// LAME tag : encoder(9) + revision(1) + lowpass(1) + replay gain(8) + flags(1) + bitrate(1) + delay/padding(3)
+		if len(xing) >= pos+24 && bytes.Equal(xing[pos:pos+4], []byte("LAME")) {
+			delayPadding := xing[pos+21:]
+			info.encoderDelay = int(delayPadding[0])<<4 | int(delayPadding[1]>>4)
+			info.encoderPadding = int(delayPadding[1]&0x0F)<<8 | int(delayPadding[2])
+		}
+	} else if len(vbri) >= 18 && bytes.Equal(vbri[0:4], []byte("VBRI")) {
+		info.hasTagFrame = true
+		info.frames = int(binary.BigEndian.Uint32(vbri[14:18]))
+	}
+
+	return info, nil
+}
+
+// mp3ValidHeader checks that the 4 bytes header is a valid Layer III one
+func mp3ValidHeader(header []byte) bool {
+	version := (header[1] >> 3) & 0x03
+	layer := (header[1] >> 1) & 0x03
+	bitrate := header[2] >> 4
+	sampleRate := (header[2] >> 2) & 0x03
+	return version != 1 && layer == 1 && bitrate != 0 && bitrate != 0x0F && sampleRate != 0x03
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"bytes"
+	fmt "fmt"
+
+	oggvorbis "github.com/jfreymuth/oggvorbis"
+)
+
+func init() {
+	RegisterDecoder("vorbis", []byte("OggS"), nil, &vorbisDecoder{})
+}
+
+// vorbisDecoder decodes Ogg Vorbis streams incrementally
+type vorbisDecoder struct{}
+
+func (d *vorbisDecoder) Decode(data []byte) (SampleReader, error) {
+	reader, err := oggvorbis.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("vorbis: %s", err)
+	}
+	return &vorbisReader{
+		reader: reader,
+	}, nil
+}
+
+// vorbisReader is a SampleReader and SampleSeeker converting decoded float samples to 16 bits
+type vorbisReader struct {
+	reader *oggvorbis.Reader
+	buffer []float32
+}
+
+func (r *vorbisReader) Channels() int {
+	return r.reader.Channels()
+}
+
+func (r *vorbisReader) SampleRate() int {
+	return r.reader.SampleRate()
+}
+
+func (r *vorbisReader) Length() int64 {
+	return r.reader.Length()
+}
+
+func (r *vorbisReader) SetPosition(position int64) error {
+	return r.reader.SetPosition(position)
+}
+
+func (r *vorbisReader) Read(samples []int16) (int, error) {
+	if len(r.buffer) < len(samples) {
+		r.buffer = make([]float32, len(samples))
+	}
+	n, err := r.reader.Read(r.buffer[:len(samples)])
+	for i, v := range r.buffer[:n] {
+		samples[i] = floatToInt16(float64(v))
+	}
+	return n, err
+}
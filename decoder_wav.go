@@ -0,0 +1,182 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	fmt "fmt"
+	"math"
+)
+
+// WAVE format tags, see https://docs.microsoft.com/en-us/windows/win32/api/mmreg/ns-mmreg-waveformatex
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+func init() {
+	RegisterDecoder("wav", []byte("RIFF\x00\x00\x00\x00WAVE"), []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}, DecoderFunc(decodeWav))
+}
+
+// wavFormat holds the content of the 'fmt ' chunk
+type wavFormat struct {
+	tag           uint16
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+	blockAlign    int
+}
+
+// decodeWav decodes a RIFF/WAVE file (PCM 8/16/24/32 bits and IEEE float 32/64 bits) into
+// interleaved 16 bits samples, returns samples, number of channels and sample rate
+func decodeWav(in []byte) ([]int16, int, int, error) {
+	if len(in) < 12 || !bytes.Equal(in[0:4], []byte("RIFF")) || !bytes.Equal(in[8:12], []byte("WAVE")) {
+		return nil, 0, 0, fmt.Errorf("wav: invalid RIFF/WAVE header")
+	}
+
+	var format *wavFormat
+	var data []byte
+
+	// Walk chunks
+	chunks := in[12:]
+	for len(chunks) >= 8 && data == nil {
+		chunkID := string(chunks[0:4])
+		chunkSize := int(binary.LittleEndian.Uint32(chunks[4:8]))
+		chunks = chunks[8:]
+		if chunkSize > len(chunks) {
+			// Truncated files are common (streamed recordings), keep what is available
+			chunkSize = len(chunks)
+		}
+		switch chunkID {
+		case "fmt ":
+			var err error
+			if format, err = parseWavFormat(chunks[:chunkSize]); err != nil {
+				return nil, 0, 0, err
+			}
+		case "data":
+			if format == nil {
+				return nil, 0, 0, fmt.Errorf("wav: data chunk found before fmt chunk")
+			}
+			data = chunks[:chunkSize]
+		}
+		// Chunks are word aligned
+		if chunkSize%2 == 1 && chunkSize < len(chunks) {
+			chunkSize++
+		}
+		chunks = chunks[chunkSize:]
+	}
+
+	if format == nil {
+		return nil, 0, 0, fmt.Errorf("wav: missing fmt chunk")
+	}
+	if data == nil {
+		return nil, 0, 0, fmt.Errorf("wav: missing data chunk")
+	}
+
+	return wavToInt16(format, data), format.channels, format.sampleRate, nil
+}
+
+func parseWavFormat(chunk []byte) (*wavFormat, error) {
+	if len(chunk) < 16 {
+		return nil, fmt.Errorf("wav: fmt chunk too short")
+	}
+	format := &wavFormat{
+		tag:           binary.LittleEndian.Uint16(chunk[0:2]),
+		channels:      int(binary.LittleEndian.Uint16(chunk[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(chunk[4:8])),
+		blockAlign:    int(binary.LittleEndian.Uint16(chunk[12:14])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(chunk[14:16])),
+	}
+
+	// WAVE_FORMAT_EXTENSIBLE stores the real format in the 2 first bytes of the sub format GUID
+	if format.tag == wavFormatExtensible {
+		if len(chunk) < 40 {
+			return nil, fmt.Errorf("wav: extensible fmt chunk too short")
+		}
+		format.tag = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+
+	switch format.tag {
+	case wavFormatPCM:
+		switch format.bitsPerSample {
+		case 8, 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("wav: unsupported PCM bit depth %d", format.bitsPerSample)
+		}
+	case wavFormatIEEEFloat:
+		switch format.bitsPerSample {
+		case 32, 64:
+		default:
+			return nil, fmt.Errorf("wav: unsupported IEEE float bit depth %d", format.bitsPerSample)
+		}
+	default:
+		return nil, fmt.Errorf("wav: unsupported format tag 0x%04X (only PCM and IEEE float are supported)", format.tag)
+	}
+
+	if format.channels < 1 || format.channels > 2 {
+		return nil, fmt.Errorf("wav: unsupported number of channels %d (mono and stereo only)", format.channels)
+	}
+	if format.sampleRate <= 0 {
+		return nil, fmt.Errorf("wav: invalid sample rate %d", format.sampleRate)
+	}
+	if format.blockAlign != format.channels*format.bitsPerSample/8 {
+		return nil, fmt.Errorf("wav: invalid block align %d", format.blockAlign)
+	}
+	return format, nil
+}
+
+func wavToInt16(format *wavFormat, data []byte) []int16 {
+	bytesPerSample := format.bitsPerSample / 8
+	count := (len(data) / format.blockAlign) * format.channels
+	samples := make([]int16, count)
+
+	switch format.tag {
+	case wavFormatPCM:
+		switch format.bitsPerSample {
+		case 8:
+			// 8 bits PCM is unsigned
+			for i := range samples {
+				samples[i] = int16(int(data[i])-128) << 8
+			}
+		case 16:
+			for i := range samples {
+				samples[i] = int16(binary.LittleEndian.Uint16(data[2*i:]))
+			}
+		case 24:
+			for i := range samples {
+				b := data[3*i:]
+				samples[i] = int16(uint16(b[1]) | uint16(b[2])<<8)
+			}
+		case 32:
+			for i := range samples {
+				samples[i] = int16(int32(binary.LittleEndian.Uint32(data[4*i:])) >> 16)
+			}
+		}
+	case wavFormatIEEEFloat:
+		for i := range samples {
+			var v float64
+			if bytesPerSample == 4 {
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+			} else {
+				v = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+			}
+			samples[i] = floatToInt16(v)
+		}
+	}
+
+	return samples
+}
+
+// floatToInt16 converts a [-1, 1] sample into a 16 bits one with clipping
+func floatToInt16(v float64) int16 {
+	if v >= 1 {
+		return math.MaxInt16
+	} else if v <= -1 {
+		return -math.MaxInt16
+	}
+	return int16(v * math.MaxInt16)
+}
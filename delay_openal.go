@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+)
+
+// -------------------------------------------------------------------- //
+// DelayNode implementation
+// -------------------------------------------------------------------- //
+
+// delayNode is rendered in software using a ring buffer, in a cycle its output is read before
+// its input is written so the delay is at least one block
+type delayNode struct {
+	processorNode
+	delayTime *audioParam
+	maxDelay  float64
+	// line holds past inputs, write is the position of current block in line
+	line  [2][]float32
+	write int
+	// input is the mix of inputs for current block
+	input renderBlock
+	// cycleOutput is the output read before input in cycles
+	cycleOutput renderBlock
+	cycleFrame  int64
+}
+
+func (n *delayNode) DelayTime(value float32) {
+	n.delayTime.SetValue(value)
+}
+
+func (n *delayNode) DelayTimeParam() AudioParam {
+	return n.delayTime
+}
+
+// render writes inputs in line and reads delayed output
+func (n *delayNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(&n.input, false)
+	cycled := n.cycleFrame == renderFrame
+	if cycled {
+		*out = n.cycleOutput
+	}
+	length := len(n.line[0])
+	for c := range n.line {
+		for i, v := range n.input[c] {
+			n.line[c][(n.write+i)%length] = v
+		}
+	}
+	if !cycled {
+		n.read(out, n.delayFrames())
+	}
+	n.write = (n.write + renderBlockSize) % length
+}
+
+// renderCycle returns the output of current block when pulled from a cycle, before input is known
+func (n *delayNode) renderCycle() *renderBlock {
+	if n.cycleFrame != renderFrame {
+		n.cycleFrame = renderFrame
+		n.read(&n.cycleOutput, math.Max(n.delayFrames(), renderBlockSize))
+	}
+	return &n.cycleOutput
+}
+
+// delayFrames returns the delay time at current render time in frames
+func (n *delayNode) delayFrames() float64 {
+	delay := float64(n.delayTime.renderValue(renderTime)) * renderSampleRate
+	return math.Max(0, math.Min(delay, n.maxDelay))
+}
+
+// read reads current block delayed by delay frames into out with linear interpolation
+func (n *delayNode) read(out *renderBlock, delay float64) {
+	length := len(n.line[0])
+	for i := 0; i < renderBlockSize; i++ {
+		position := float64(n.write+i+length) - delay
+		k := int(position)
+		frac := float32(position - float64(k))
+		k0, k1 := k%length, (k+1)%length
+		for c := range out {
+			v := n.line[c][k0]
+			out[c][i] = v + (n.line[c][k1]-v)*frac
+		}
+	}
+}
+
+func createDelayNode(maxDelay float32) (DelayNode, error) {
+	delay := &delayNode{
+		processorNode: newProcessorNode(),
+		maxDelay:      float64(maxDelay) * renderSampleRate,
+	}
+	// Room for max delay, current block and interpolation
+	length := int(math.Ceil(delay.maxDelay)) + renderBlockSize + 2
+	for c := range delay.line {
+		delay.line[c] = make([]float32, length)
+	}
+	delay.delayTime = newAudioParam(0, 0, maxDelay, nil)
+	delay.process = delay.render
+	delay.cycle = delay.renderCycle
+	return delay, nil
+}
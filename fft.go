@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	sync "sync"
+)
+
+// -------------------------------------------------------------------- //
+// FFT
+// -------------------------------------------------------------------- //
+
+var fftTwiddlesMutex sync.Mutex
+var fftTwiddlesCache = make(map[int][]complex128)
+
+// fftTwiddles returns the twiddle factors exp(-2iπk/n) for k in [0, n/2)
+func fftTwiddles(n int) []complex128 {
+	fftTwiddlesMutex.Lock()
+	defer fftTwiddlesMutex.Unlock()
+	if twiddles, ok := fftTwiddlesCache[n]; ok {
+		return twiddles
+	}
+	twiddles := make([]complex128, n/2)
+	for k := range twiddles {
+		twiddles[k] = cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
+	}
+	fftTwiddlesCache[n] = twiddles
+	return twiddles
+}
+
+// fft computes in place the discrete Fourier transform of x, len(x) must be a power of 2,
+// inverse computes the unscaled inverse transform
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	if n < 2 {
+		return
+	}
+	// Bit reversal permutation
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	// Butterflies
+	twiddles := fftTwiddles(n)
+	for size := 2; size <= n; size <<= 1 {
+		half, step := size>>1, n/size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				w := twiddles[k*step]
+				if inverse {
+					w = cmplx.Conj(w)
+				}
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k], x[start+k+half] = a+b, a-b
+			}
+		}
+	}
+}
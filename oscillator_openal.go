@@ -0,0 +1,222 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	fmt "fmt"
+	"math"
+)
+
+// -------------------------------------------------------------------- //
+// OscillatorNode implementation
+// -------------------------------------------------------------------- //
+
+const (
+	// Number of samples of one period in wave tables
+	oscillatorTableSize = 4096
+	// Number of band-limited tables of each wave, table k holds harmonics up to oscillatorTableSize/2 >> k
+	oscillatorTableCount = 12
+)
+
+// periodicWave holds the Fourier coefficients of a waveform and its band-limited wave tables, tables are
+// built on demand by render
+type periodicWave struct {
+	real, imag []float64
+	// scale normalizes the waveform to a peak of 1
+	scale  float64
+	tables [oscillatorTableCount][]float32
+}
+
+func newPeriodicWave(real, imag []float64) *periodicWave {
+	w := &periodicWave{real: real, imag: imag, scale: 1}
+	peak := 0.0
+	for _, v := range w.synthesize(0) {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	if peak > 0 {
+		w.scale = 1 / peak
+	}
+	return w
+}
+
+// synthesize computes one period of the waveform limited to the harmonics of table k
+func (w *periodicWave) synthesize(k int) []float64 {
+	x := make([]complex128, oscillatorTableSize)
+	limit := oscillatorTableSize / 2 >> uint(k)
+	if limit >= oscillatorTableSize/2 {
+		limit = oscillatorTableSize/2 - 1
+	}
+	for h := 1; h <= limit && h < len(w.real); h++ {
+		// Real part of the inverse transform gives real*cos + imag*sin
+		x[h] = complex(w.real[h], -w.imag[h])
+	}
+	fft(x, true)
+	samples := make([]float64, oscillatorTableSize)
+	for i, v := range x {
+		samples[i] = real(v)
+	}
+	return samples
+}
+
+// table returns the wave table to play at frequency without aliasing, nil above Nyquist frequency.
+// Must be called with graphMutex held.
+func (w *periodicWave) table(frequency float64) []float32 {
+	harmonics := float64(renderSampleRate) / 2 / math.Abs(frequency)
+	k := 0
+	for ; k < oscillatorTableCount && float64(int(oscillatorTableSize/2)>>uint(k)) > harmonics; k++ {
+	}
+	if k == oscillatorTableCount {
+		return nil
+	}
+	if w.tables[k] == nil {
+		table := make([]float32, oscillatorTableSize)
+		for i, v := range w.synthesize(k) {
+			table[i] = float32(v * w.scale)
+		}
+		w.tables[k] = table
+	}
+	return w.tables[k]
+}
+
+// oscillatorWaves caches the waves of built-in types, protected by graphMutex
+var oscillatorWaves = make(map[OscillatorType]*periodicWave)
+
+// oscillatorWave returns the wave of a built-in type, coefficients follow the WebAudio specification.
+// Must be called with graphMutex held.
+func oscillatorWave(oscillatorType OscillatorType) *periodicWave {
+	if w, ok := oscillatorWaves[oscillatorType]; ok {
+		return w
+	}
+	real := make([]float64, oscillatorTableSize/2)
+	imag := make([]float64, oscillatorTableSize/2)
+	for h := 1; h < len(imag); h++ {
+		n := float64(h)
+		switch oscillatorType {
+		case OscillatorSine:
+			if h == 1 {
+				imag[h] = 1
+			}
+		case OscillatorSquare:
+			if h%2 == 1 {
+				imag[h] = 4 / (n * math.Pi)
+			}
+		case OscillatorSawtooth:
+			imag[h] = 2 / (n * math.Pi)
+			if h%2 == 0 {
+				imag[h] = -imag[h]
+			}
+		case OscillatorTriangle:
+			imag[h] = 8 * math.Sin(n*math.Pi/2) / (n * n * math.Pi * math.Pi)
+		}
+	}
+	w := newPeriodicWave(real, imag)
+	oscillatorWaves[oscillatorType] = w
+	return w
+}
+
+// oscillatorNode is a source rendered in software
+type oscillatorNode struct {
+	softwareSourceNode
+	frequency *audioParam
+	detune    *audioParam
+	wave      *periodicWave
+	// phase is the position in wave tables
+	phase float64
+}
+
+func (n *oscillatorNode) Type(oscillatorType OscillatorType) {
+	if oscillatorType < OscillatorSine || oscillatorType >= OscillatorCustom {
+		return
+	}
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.wave = oscillatorWave(oscillatorType)
+}
+
+func (n *oscillatorNode) Frequency(value float32) {
+	n.frequency.SetValue(value)
+}
+
+func (n *oscillatorNode) FrequencyParam() AudioParam {
+	return n.frequency
+}
+
+func (n *oscillatorNode) Detune(cents float32) {
+	n.detune.SetValue(cents)
+}
+
+func (n *oscillatorNode) DetuneParam() AudioParam {
+	return n.detune
+}
+
+func (n *oscillatorNode) SetPeriodicWave(real, imag []float32) error {
+	if len(real) != len(imag) || len(real) < 2 {
+		return fmt.Errorf("invalid periodic wave coefficients (%d, %d)", len(real), len(imag))
+	}
+	realCoefs := make([]float64, len(real))
+	imagCoefs := make([]float64, len(imag))
+	for i := range real {
+		realCoefs[i] = float64(real[i])
+		imagCoefs[i] = float64(imag[i])
+	}
+	wave := newPeriodicWave(realCoefs, imagCoefs)
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.wave = wave
+	return nil
+}
+
+// render synthesizes the wave between start and stop times, frequency is computed for each frame
+// only if modulated
+func (n *oscillatorNode) render(out *renderBlock, wet bool) {
+	*out = silentBlock
+	first, last := n.renderRange()
+	if first == last {
+		return
+	}
+	modulated := len(n.frequency.inputs) > 0 || len(n.detune.inputs) > 0
+	var frequencies, detunes *[renderBlockSize]float32
+	if modulated {
+		frequencies, detunes = n.frequency.renderValues(), n.detune.renderValues()
+	}
+	frequency := float64(n.frequency.renderValue(renderTime)) * math.Pow(2, float64(n.detune.renderValue(renderTime))/1200)
+	table := n.wave.table(frequency)
+	step := frequency / renderSampleRate * oscillatorTableSize
+	for i := first; i < last; i++ {
+		if modulated {
+			frequency = float64(frequencies[i]) * math.Pow(2, float64(detunes[i])/1200)
+			table = n.wave.table(frequency)
+			step = frequency / renderSampleRate * oscillatorTableSize
+		}
+		if table != nil {
+			k := int(n.phase)
+			v := table[k] + (table[(k+1)%oscillatorTableSize]-table[k])*float32(n.phase-float64(k))
+			out[0][i], out[1][i] = v, v
+		}
+		n.phase += step
+		if n.phase >= oscillatorTableSize || n.phase < 0 {
+			n.phase -= oscillatorTableSize * math.Floor(n.phase/oscillatorTableSize)
+			// Phases just below 0 round to oscillatorTableSize
+			if n.phase >= oscillatorTableSize {
+				n.phase = 0
+			}
+		}
+	}
+}
+
+func createOscillatorNode() (OscillatorNode, error) {
+	oscillator := &oscillatorNode{
+		softwareSourceNode: newSoftwareSourceNode(),
+	}
+	oscillator.source = oscillator
+	nyquist := float32(renderSampleRate) / 2
+	oscillator.frequency = newAudioParam(440, -nyquist, nyquist, nil)
+	oscillator.detune = newAudioParam(0, -153600, 153600, nil)
+	graphMutex.Lock()
+	oscillator.wave = oscillatorWave(OscillatorSine)
+	graphMutex.Unlock()
+	oscillator.process = oscillator.render
+	return oscillator, nil
+}
@@ -0,0 +1,426 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+	"sort"
+	sync "sync"
+	time "time"
+)
+
+// -------------------------------------------------------------------- //
+// AudioParam implementation
+// -------------------------------------------------------------------- //
+
+// Interval between 2 evaluations of automated params, values applied through AL change by steps
+// of this duration
+const automationInterval = 2 * time.Millisecond
+
+// Number of rendered blocks kept to apply modulation of params at control rate
+const modulationHistorySize = 64
+
+type paramEventType int
+
+const (
+	paramEventSetValue paramEventType = iota
+	paramEventLinearRamp
+	paramEventExponentialRamp
+	paramEventSetTarget
+	paramEventValueCurve
+)
+
+type paramEvent struct {
+	kind         paramEventType
+	value        float32
+	time         float64
+	timeConstant float64
+	duration     float64
+	curve        []float32
+}
+
+// audioParam evaluates a timeline of events and applies computed values through apply callback
+type audioParam struct {
+	mutex sync.Mutex
+	// value is the value at time before any event
+	value float32
+	time  float64
+	// current is the last computed value
+	current  float32
+	minValue float32
+	maxValue float32
+	events   []paramEvent
+	apply    func(value float32)
+	// inputs holds nodes connected to param, protected by graphMutex
+	inputs []*node
+	// values holds the values of current block computed by software rendering
+	frame  int64
+	values [renderBlockSize]float32
+	// modulated is set while nodes are connected, modulation holds the mean input of recent
+	// blocks to apply modulation at control rate through apply callback
+	modulated      bool
+	modulation     [modulationHistorySize]paramModulation
+	modulationHead int
+}
+
+// paramModulation is the mean input of a param during the block rendered at time
+type paramModulation struct {
+	time  float64
+	value float32
+}
+
+func newAudioParam(value, minValue, maxValue float32, apply func(value float32)) *audioParam {
+	return &audioParam{
+		value:    value,
+		current:  value,
+		minValue: minValue,
+		maxValue: maxValue,
+		events:   make([]paramEvent, 0, 4),
+		apply:    apply,
+	}
+}
+
+func (p *audioParam) Value() float32 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.current
+}
+
+func (p *audioParam) SetValue(value float32) {
+	p.schedule(paramEvent{kind: paramEventSetValue, value: value, time: currentTime()})
+}
+
+func (p *audioParam) SetValueAtTime(value float32, startTime float64) {
+	p.schedule(paramEvent{kind: paramEventSetValue, value: value, time: startTime})
+}
+
+func (p *audioParam) LinearRampToValueAtTime(value float32, endTime float64) {
+	p.schedule(paramEvent{kind: paramEventLinearRamp, value: value, time: endTime})
+}
+
+func (p *audioParam) ExponentialRampToValueAtTime(value float32, endTime float64) {
+	if value == 0 {
+		value = exponentialRampMinValue
+	}
+	p.schedule(paramEvent{kind: paramEventExponentialRamp, value: value, time: endTime})
+}
+
+func (p *audioParam) SetTargetAtTime(target float32, startTime float64, timeConstant float32) {
+	if timeConstant <= 0 {
+		p.SetValueAtTime(target, startTime)
+		return
+	}
+	p.schedule(paramEvent{kind: paramEventSetTarget, value: target, time: startTime, timeConstant: float64(timeConstant)})
+}
+
+func (p *audioParam) SetValueCurveAtTime(values []float32, startTime float64, duration float32) {
+	if len(values) < 2 || duration <= 0 {
+		return
+	}
+	curve := make([]float32, len(values))
+	copy(curve, values)
+	p.schedule(paramEvent{kind: paramEventValueCurve, value: curve[len(curve)-1], time: startTime, duration: float64(duration), curve: curve})
+}
+
+func (p *audioParam) CancelScheduledValues(startTime float64) {
+	p.mutex.Lock()
+	for i, e := range p.events {
+		if e.time >= startTime {
+			p.events = p.events[:i]
+			break
+		}
+	}
+	p.mutex.Unlock()
+	p.update(currentTime())
+}
+
+// renderValue computes the value of the param at time t for software rendering, modulation is the one
+// of the first frame of current block. Must be called with graphMutex held.
+func (p *audioParam) renderValue(t float64) float32 {
+	if len(p.inputs) > 0 {
+		return p.renderValues()[0]
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.valueAt(t)
+}
+
+// renderValues computes the values of the param for each frame of current block, outputs of connected nodes
+// are mixed down to mono and added to the automated value. Must be called with graphMutex held.
+func (p *audioParam) renderValues() *[renderBlockSize]float32 {
+	if p.frame == renderFrame {
+		return &p.values
+	}
+	p.frame = renderFrame
+	p.mutex.Lock()
+	if len(p.events) == 0 {
+		value := p.valueAt(renderTime)
+		for i := range p.values {
+			p.values[i] = value
+		}
+	} else {
+		// Automated at audio rate
+		for i := range p.values {
+			p.values[i] = p.valueAt(renderTime + float64(i)/renderSampleRate)
+		}
+	}
+	p.mutex.Unlock()
+	if len(p.inputs) == 0 {
+		return &p.values
+	}
+
+	sum := float32(0)
+	for _, input := range p.inputs {
+		in := input.pull(false)
+		for i := range p.values {
+			v := (in[0][i] + in[1][i]) / 2
+			p.values[i] += v
+			sum += v
+		}
+	}
+	for i, v := range p.values {
+		p.values[i] = p.clamp(v)
+	}
+
+	p.mutex.Lock()
+	p.modulationHead = (p.modulationHead + 1) % modulationHistorySize
+	p.modulation[p.modulationHead] = paramModulation{time: renderTime, value: sum / renderBlockSize}
+	p.mutex.Unlock()
+	return &p.values
+}
+
+// modulationAt returns the mean input of the last block rendered before time t
+func (p *audioParam) modulationAt(t float64) float32 {
+	for i := 0; i < modulationHistorySize; i++ {
+		m := p.modulation[(p.modulationHead+modulationHistorySize-i)%modulationHistorySize]
+		if m.time <= t {
+			return m.value
+		}
+	}
+	return 0
+}
+
+// connect adds a connected node, the param is updated at control rate while connected. Must be called
+// with graphMutex held.
+func (p *audioParam) connect(n *node) {
+	p.inputs = append(p.inputs, n)
+	p.mutex.Lock()
+	p.modulated = true
+	p.mutex.Unlock()
+	startAutomation(p)
+}
+
+// disconnect removes a connected node. Must be called with graphMutex held.
+func (p *audioParam) disconnect(n *node) {
+	for i, input := range p.inputs {
+		if input == n {
+			p.inputs = append(p.inputs[:i], p.inputs[i+1:]...)
+			break
+		}
+	}
+	if len(p.inputs) == 0 {
+		p.mutex.Lock()
+		p.modulated = false
+		p.modulation = [modulationHistorySize]paramModulation{}
+		p.mutex.Unlock()
+		// Restores automated value
+		startAutomation(p)
+	}
+}
+
+// schedule inserts an event in timeline, events at the same time are kept in insertion order
+func (p *audioParam) schedule(event paramEvent) {
+	p.mutex.Lock()
+	i := sort.Search(len(p.events), func(i int) bool {
+		return p.events[i].time > event.time
+	})
+	p.events = append(p.events, paramEvent{})
+	copy(p.events[i+1:], p.events[i:])
+	p.events[i] = event
+	p.mutex.Unlock()
+
+	if p.update(currentTime()) {
+		startAutomation(p)
+	}
+}
+
+// update evaluates the param at given time, applies the value and drops finished events,
+// returns true if events remain in timeline
+func (p *audioParam) update(now float64) bool {
+	p.mutex.Lock()
+	value := p.valueAt(now)
+	if p.modulated {
+		value = p.clamp(value + p.modulationAt(now))
+	}
+	p.collapse(now)
+	changed := value != p.current
+	p.current = value
+	active := len(p.events) > 0 || p.modulated
+	apply := p.apply
+	p.mutex.Unlock()
+
+	if changed && apply != nil {
+		apply(value)
+	}
+	return active
+}
+
+// valueAt computes the value of the param at time t
+func (p *audioParam) valueAt(t float64) float32 {
+	v, vTime := p.value, p.time
+	for i, e := range p.events {
+		switch e.kind {
+		case paramEventLinearRamp:
+			if t < e.time {
+				if t <= vTime || e.time <= vTime {
+					return p.clamp(v)
+				}
+				return p.clamp(v + (e.value-v)*float32((t-vTime)/(e.time-vTime)))
+			}
+		case paramEventExponentialRamp:
+			if t < e.time {
+				// Ramps between values of different signs or zero are not possible, hold value
+				if t <= vTime || e.time <= vTime || v*e.value <= 0 {
+					return p.clamp(v)
+				}
+				return p.clamp(v * float32(math.Pow(float64(e.value/v), (t-vTime)/(e.time-vTime))))
+			}
+		default:
+			if t < e.time {
+				return p.clamp(v)
+			}
+			switch e.kind {
+			case paramEventSetTarget:
+				end := math.Inf(1)
+				if i+1 < len(p.events) {
+					end = p.events[i+1].time
+				}
+				if t < end {
+					return p.clamp(targetValue(v, e, t))
+				}
+				v = targetValue(v, e, end)
+				vTime = end
+				continue
+			case paramEventValueCurve:
+				if t < e.time+e.duration {
+					return p.clamp(curveValue(e, t))
+				}
+			}
+		}
+		v, vTime = e.value, e.time+e.duration
+	}
+	return p.clamp(v)
+}
+
+// collapse removes events finished before now, the next event must not depend on them (ramps)
+func (p *audioParam) collapse(now float64) {
+	count := 0
+	for i, e := range p.events {
+		end := e.time + e.duration
+		if e.kind == paramEventSetTarget {
+			// Target approach ends at next event or once target is reached (10 time constants)
+			if i+1 < len(p.events) {
+				end = p.events[i+1].time
+			} else {
+				end = e.time + 10*e.timeConstant
+			}
+		}
+		if end > now {
+			break
+		}
+		if i+1 < len(p.events) {
+			if next := p.events[i+1]; next.kind == paramEventLinearRamp || next.kind == paramEventExponentialRamp {
+				if next.time > now {
+					break
+				}
+			}
+		}
+		count = i + 1
+	}
+	for i, e := range p.events[:count] {
+		if e.kind == paramEventSetTarget && i+1 < len(p.events) {
+			end := p.events[i+1].time
+			p.value = targetValue(p.value, e, end)
+			p.time = end
+		} else if e.kind == paramEventSetTarget {
+			p.value = e.value
+			p.time = e.time + 10*e.timeConstant
+		} else {
+			p.value = e.value
+			p.time = e.time + e.duration
+		}
+	}
+	if count > 0 {
+		p.events = append(p.events[:0], p.events[count:]...)
+	}
+}
+
+func (p *audioParam) clamp(v float32) float32 {
+	if v < p.minValue {
+		return p.minValue
+	} else if v > p.maxValue {
+		return p.maxValue
+	}
+	return v
+}
+
+func targetValue(v float32, e paramEvent, t float64) float32 {
+	return e.value + (v-e.value)*float32(math.Exp(-(t-e.time)/e.timeConstant))
+}
+
+func curveValue(e paramEvent, t float64) float32 {
+	position := (t - e.time) / e.duration * float64(len(e.curve)-1)
+	k := int(position)
+	if k >= len(e.curve)-1 {
+		return e.curve[len(e.curve)-1]
+	}
+	return e.curve[k] + (e.curve[k+1]-e.curve[k])*float32(position-float64(k))
+}
+
+// Automation
+
+var automationMutex sync.Mutex
+var automatedParams = make(map[*audioParam]bool)
+var automationRunning bool
+
+// startAutomation adds param to automated ones and starts automation loop if needed
+func startAutomation(p *audioParam) {
+	automationMutex.Lock()
+	defer automationMutex.Unlock()
+	automatedParams[p] = true
+	if !automationRunning {
+		automationRunning = true
+		go automationLoop()
+	}
+}
+
+// automationLoop updates automated params until none is left
+func automationLoop() {
+	ticker := time.NewTicker(automationInterval)
+	defer ticker.Stop()
+	params := make([]*audioParam, 0, 16)
+	for range ticker.C {
+		automationMutex.Lock()
+		if len(automatedParams) == 0 {
+			automationRunning = false
+			automationMutex.Unlock()
+			return
+		}
+		params = params[:0]
+		for p := range automatedParams {
+			params = append(params, p)
+		}
+		automationMutex.Unlock()
+
+		now := currentTime()
+		for _, p := range params {
+			if !p.update(now) {
+				automationMutex.Lock()
+				delete(automatedParams, p)
+				automationMutex.Unlock()
+			}
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	"math"
+	time "time"
+
+	al "github.com/thommil/tge-mobile/exp/audio/al"
+)
+
+// -------------------------------------------------------------------- //
+// Software rendering
+// -------------------------------------------------------------------- //
+
+// Nodes without OpenAL counterpart (filters, effects, synthesized sources) are rendered in software
+// by pulling blocks of samples from the destination node through the graph. Sources played by AL are
+// rendered in software only when connected to such nodes, the resulting mix is streamed on a
+// dedicated AL source.
+
+const (
+	// Sample rate of software rendering
+	renderSampleRate = 44100
+	// Number of frames rendered at once
+	renderBlockSize = 128
+	// Number of blocks in each AL buffer queued on render source
+	renderBufferBlocks = 8
+	// Number of AL buffers queued on render source
+	renderBufferCount = 4
+	// Interval between 2 refills of render source
+	renderRefillInterval = 10 * time.Millisecond
+)
+
+// renderBlock holds one block of stereo samples
+type renderBlock [2][renderBlockSize]float32
+
+var silentBlock renderBlock
+
+// renderCache holds the output of a node for a block
+type renderCache struct {
+	frame     int64
+	output    renderBlock
+	rendering bool
+}
+
+// renderFrame is the index of the block being rendered, outputs of nodes are computed once per block
+var renderFrame int64
+
+// renderTime is the audio context time of the block being rendered
+var renderTime float64
+
+// renderDone stops the render loop, nil if not started
+var renderDone chan bool
+
+// renderEnded holds the end handlers of software sources which have ended while rendering, they are called
+// once graphMutex is released
+var renderEnded []func()
+
+// pull returns the output of node for current block, wet restricts rendering to signals not played by
+// AL sources. Must be called with graphMutex held.
+func (n *node) pull(wet bool) *renderBlock {
+	cache := &n.caches[0]
+	if wet && !n.rendered {
+		cache = &n.caches[1]
+	}
+	if cache.frame == renderFrame {
+		return &cache.output
+	}
+	if cache.rendering {
+		if n.cycle != nil {
+			return n.cycle()
+		}
+		// Cycles without delay are muted
+		return &silentBlock
+	}
+	cache.rendering = true
+	if n.process != nil {
+		n.process(&cache.output, wet)
+	} else {
+		cache.output = silentBlock
+	}
+	cache.rendering = false
+	cache.frame = renderFrame
+	return &cache.output
+}
+
+// mixInputs sums outputs of connected nodes into out
+func (n *node) mixInputs(out *renderBlock, wet bool) {
+	*out = silentBlock
+	for _, input := range n.inputs {
+		in := input.pull(wet)
+		for c := range out {
+			for i, v := range in[c] {
+				out[c][i] += v
+			}
+		}
+	}
+}
+
+// processorNode is embedded by nodes rendered in software only, connected AL sources are rendered in
+// software and not forwarded to next nodes
+type processorNode struct {
+	node
+}
+
+func (n *processorNode) onConnectStateChanged(connected bool, sources []*sourceProxy) {
+}
+
+// newProcessorNode creates a processorNode and starts software rendering
+func newProcessorNode() processorNode {
+	startRenderEngine()
+	return processorNode{
+		node: node{
+			sources:  make([]*sourceProxy, 0),
+			to:       make([]connectListener, 0, 1),
+			rendered: true,
+		},
+	}
+}
+
+// softwareSourceNode is embedded by sources rendered in software, it handles start and stop times
+type softwareSourceNode struct {
+	processorNode
+	// source is the embedding node, passed to ended callback and event
+	source    Node
+	onEnded   func()
+	started   bool
+	playing   bool
+	startTime float64
+	stopTime  float64
+}
+
+// newSoftwareSourceNode creates a softwareSourceNode and starts software rendering
+func newSoftwareSourceNode() softwareSourceNode {
+	return softwareSourceNode{
+		processorNode: newProcessorNode(),
+		stopTime:      math.Inf(1),
+	}
+}
+
+func (n *softwareSourceNode) Start(when float64) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if n.started {
+		return
+	}
+	n.started = true
+	n.playing = true
+	n.startTime = math.Max(when, nextRenderTime())
+}
+
+func (n *softwareSourceNode) Stop(when float64) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if !n.playing {
+		return
+	}
+	n.stopTime = math.Max(when, nextRenderTime())
+}
+
+func (n *softwareSourceNode) OnEnded(callback func()) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.onEnded = callback
+}
+
+// renderRange returns the range of frames of current block between start and stop times, node ends
+// once stop time is reached
+func (n *softwareSourceNode) renderRange() (int, int) {
+	if !n.playing {
+		return 0, 0
+	}
+	first := math.Ceil((n.startTime - renderTime) * renderSampleRate)
+	last := math.Ceil((n.stopTime - renderTime) * renderSampleRate)
+	first = math.Max(0, math.Min(first, renderBlockSize))
+	last = math.Max(0, math.Min(last, renderBlockSize))
+	if last < renderBlockSize {
+		n.playing = false
+		renderEnded = append(renderEnded, n.end)
+	}
+	if last < first {
+		last = first
+	}
+	return int(first), int(last)
+}
+
+// end disconnects node once stopped
+func (n *softwareSourceNode) end() {
+	graphMutex.Lock()
+	n.disconnectAll()
+	onEnded := n.onEnded
+	graphMutex.Unlock()
+	fireEnded(n.source, onEnded)
+}
+
+// nextRenderTime returns the audio context time of the next rendered block, sources started
+// immediately in software start at this time. Must be called with graphMutex held.
+func nextRenderTime() float64 {
+	if renderDone == nil {
+		return currentTime()
+	}
+	return renderTime + float64(renderBlockSize)/renderSampleRate
+}
+
+// startRenderEngine starts software rendering, called by factories of software nodes
+func startRenderEngine() {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if renderDone != nil {
+		return
+	}
+	source := al.GenSources(1)[0]
+	source.Setf(0x202, 1) //AL_SOURCE_RELATIVE
+	source.SetGain(1)
+	renderDone = make(chan bool)
+	renderTime = currentTime()
+	go renderLoop(source, al.GenBuffers(renderBufferCount), renderDone)
+}
+
+// stopRenderEngine stops software rendering and waits for AL resources to be released
+func stopRenderEngine() {
+	graphMutex.Lock()
+	done := renderDone
+	renderDone = nil
+	graphMutex.Unlock()
+	if done != nil {
+		done <- true
+		<-done
+	}
+}
+
+// renderLoop renders blocks into processed buffers of source until notified on done, done is closed
+// once resources are released
+func renderLoop(source al.Source, buffers []al.Buffer, done chan bool) {
+	ticker := time.NewTicker(renderRefillInterval)
+	defer ticker.Stop()
+	free := append(make([]al.Buffer, 0, renderBufferCount), buffers...)
+	samples := make([]int16, 2*renderBlockSize*renderBufferBlocks)
+	var bytes []byte
+	for {
+		select {
+		case <-done:
+			al.StopSources(source)
+			source.Seti(0x1009, 0) // AL_BUFFER, unqueue all
+			al.DeleteSources(source)
+			al.DeleteBuffers(buffers...)
+			close(done)
+			return
+		case <-ticker.C:
+		}
+
+		// Nothing is rendered while suspended
+		if clockPaused() {
+			if source.State() == al.Playing {
+				al.PauseSources(source)
+			}
+			continue
+		}
+
+		if processed := source.BuffersProcessed(); processed > 0 {
+			unqueued := make([]al.Buffer, processed)
+			source.UnqueueBuffers(unqueued...)
+			free = append(free, unqueued...)
+		}
+		for _, buffer := range free {
+			graphMutex.Lock()
+			renderBuffer(samples)
+			ended := renderEnded
+			renderEnded = nil
+			graphMutex.Unlock()
+			for _, end := range ended {
+				end()
+			}
+			bytes = putInt16Bytes(bytes, samples)
+			buffer.BufferData(al.FormatStereo16, bytes, renderSampleRate)
+			source.QueueBuffers(buffer)
+		}
+		free = free[:0]
+
+		// Start, resume or restart after underrun
+		if source.State() != al.Playing {
+			al.PlaySources(source)
+		}
+	}
+}
+
+// renderBuffer renders next blocks from destination into interleaved samples
+func renderBuffer(samples []int16) {
+	for b := 0; b < renderBufferBlocks; b++ {
+		renderFrame++
+		renderTime += float64(renderBlockSize) / renderSampleRate
+		if now := currentTime(); renderTime < now {
+			// Late after underrun or suspend
+			renderTime = now
+		}
+		var out *renderBlock
+		if masterLimiter != nil {
+			// All sources are rendered in software
+			limited := *destinationNodeSingleton.pull(false)
+			limit(&limited)
+			out = &limited
+		} else {
+			out = destinationNodeSingleton.pull(true)
+		}
+		// Analysers not connected to destination
+		for analyser := range analyserNodes {
+			analyser.capture()
+		}
+		offset := 2 * renderBlockSize * b
+		for i := 0; i < renderBlockSize; i++ {
+			samples[offset+2*i] = floatToInt16(float64(out[0][i]))
+			samples[offset+2*i+1] = floatToInt16(float64(out[1][i]))
+		}
+	}
+}
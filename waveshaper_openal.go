@@ -0,0 +1,167 @@
+// Copyright (c) 2019 Thomas MILLET. All rights reserved.
+
+// +build !js
+
+package audio
+
+import (
+	fmt "fmt"
+	"math"
+)
+
+// -------------------------------------------------------------------- //
+// WaveShaperNode implementation
+// -------------------------------------------------------------------- //
+
+// Number of taps of each phase of oversampling filters
+const waveShaperPhaseTaps = 16
+
+// waveShaperFilter is a windowed-sinc lowpass used to interpolate and decimate oversampled signals,
+// taps of phase p are at indices p, p+factor, p+2*factor...
+type waveShaperFilter struct {
+	factor int
+	taps   []float64
+}
+
+func newWaveShaperFilter(factor int) *waveShaperFilter {
+	length := factor * waveShaperPhaseTaps
+	taps := make([]float64, length)
+	center := float64(length-1) / 2
+	cutoff := 0.5 / float64(factor)
+	sum := 0.0
+	for i := range taps {
+		t := float64(i) - center
+		sinc := 2 * cutoff
+		if t != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*t) / (math.Pi * t)
+		}
+		// Blackman window
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(length-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(length-1))
+		taps[i] = sinc * w
+		sum += taps[i]
+	}
+	// Unity gain at DC
+	for i := range taps {
+		taps[i] /= sum
+	}
+	return &waveShaperFilter{factor: factor, taps: taps}
+}
+
+// waveShaperNode is rendered in software, the curve is applied on a polyphase interpolated signal which is
+// then decimated back to render rate
+type waveShaperNode struct {
+	processorNode
+	curve      []float32
+	oversample OverSampleType
+	filter     *waveShaperFilter
+	// up holds the last input samples per channel, most recent first
+	up [2][]float64
+	// down holds the last shaped samples per channel, most recent first
+	down [2][]float64
+	// shaped holds the shaped samples of one input sample
+	shaped []float64
+}
+
+func (n *waveShaperNode) SetCurve(curve []float32) error {
+	if curve != nil && len(curve) < 2 {
+		return fmt.Errorf("invalid curve length %d", len(curve))
+	}
+	var copied []float32
+	if curve != nil {
+		copied = append(make([]float32, 0, len(curve)), curve...)
+	}
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	n.curve = copied
+	return nil
+}
+
+func (n *waveShaperNode) Oversample(oversample OverSampleType) {
+	graphMutex.Lock()
+	defer graphMutex.Unlock()
+	if oversample == n.oversample {
+		return
+	}
+	n.oversample = oversample
+	n.filter = nil
+	switch oversample {
+	case OverSample2x:
+		n.filter = newWaveShaperFilter(2)
+	case OverSample4x:
+		n.filter = newWaveShaperFilter(4)
+	default:
+		n.oversample = OverSampleNone
+		return
+	}
+	for c := range n.up {
+		n.up[c] = make([]float64, waveShaperPhaseTaps)
+		n.down[c] = make([]float64, len(n.filter.taps))
+	}
+	n.shaped = make([]float64, n.filter.factor)
+}
+
+// shape maps x through curve as defined by WebAudio specification
+func (n *waveShaperNode) shape(x float64) float64 {
+	last := len(n.curve) - 1
+	v := float64(last) * (x + 1) / 2
+	switch {
+	case v <= 0 || math.IsNaN(v):
+		return float64(n.curve[0])
+	case v >= float64(last):
+		return float64(n.curve[last])
+	}
+	k := int(v)
+	f := v - float64(k)
+	return float64(n.curve[k])*(1-f) + float64(n.curve[k+1])*f
+}
+
+// render shapes inputs
+func (n *waveShaperNode) render(out *renderBlock, wet bool) {
+	n.mixInputs(out, false)
+	if n.curve == nil {
+		return
+	}
+	if n.filter == nil {
+		for c := range out {
+			for i, v := range out[c] {
+				out[c][i] = float32(n.shape(float64(v)))
+			}
+		}
+		return
+	}
+
+	factor, taps := n.filter.factor, n.filter.taps
+	for c := range out {
+		up, down := n.up[c], n.down[c]
+		for i, v := range out[c] {
+			copy(up[1:], up)
+			up[0] = float64(v)
+			// Interpolation, each phase computes one oversampled sample (gain restored by factor)
+			for p := 0; p < factor; p++ {
+				y := 0.0
+				for k, x := range up {
+					y += taps[p+k*factor] * x
+				}
+				n.shaped[p] = n.shape(y * float64(factor))
+			}
+			// Decimation, only the kept sample is computed
+			copy(down[factor:], down)
+			for p := 0; p < factor; p++ {
+				down[p] = n.shaped[factor-1-p]
+			}
+			y := 0.0
+			for k, x := range down {
+				y += taps[k] * x
+			}
+			out[c][i] = float32(y)
+		}
+	}
+}
+
+func createWaveShaperNode() (WaveShaperNode, error) {
+	shaper := &waveShaperNode{
+		processorNode: newProcessorNode(),
+	}
+	shaper.process = shaper.render
+	return shaper, nil
+}